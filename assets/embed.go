@@ -5,5 +5,8 @@ import _ "embed"
 //go:embed com.mirego.ekiden.plist.tmpl
 var EkidenPlist []byte
 
+//go:embed rvmm.service.tmpl
+var SystemdUnit []byte
+
 //go:embed config.yaml.example
 var ConfigExample []byte
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes for `rvmm config`
+const (
+	exitConfigOK      = 0
+	exitConfigInvalid = 1
+	exitConfigError   = 2
+)
+
+const configUsage = `usage: rvmm config <command> [flags]
+
+commands:
+  validate [-config path]                      check the config and list every problem
+  show [-config path] [-redact]                print the effective config and each value's source
+  set [-config path] [-include] <key> <value>  change one field, keeping the file's comments
+  diff <a> <b>                                 list fields that differ between two files
+  migrate [-config path]                       upgrade the config file to the current version
+  schema                                       print a JSON Schema for the config file
+`
+
+// configCommand dispatches `rvmm config` subcommands
+func configCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return exitConfigError
+	}
+
+	switch args[0] {
+	case "validate":
+		return configValidate(args[1:])
+	case "show":
+		return configShow(args[1:])
+	case "set":
+		return configSet(args[1:])
+	case "diff":
+		return configDiff(args[1:])
+	case "migrate":
+		return configMigrate(args[1:])
+	case "schema":
+		return configSchema()
+	default:
+		fmt.Fprint(os.Stderr, configUsage)
+		return exitConfigError
+	}
+}
+
+// configValidate exits 0 when the config is valid, 1 when it has problems
+// and 2 when it can't be loaded
+func configValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return exitConfigError
+	}
+
+	for _, warning := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	// The file may be meant for another machine, so host problems only warn
+	if err := cfg.ValidateHost(); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", problem)
+			}
+		}
+	}
+
+	var problems []config.FieldError
+	if err := cfg.Validate(); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			problems = verr.Problems
+		} else {
+			problems = []config.FieldError{{Message: err.Error()}}
+		}
+	}
+
+	source := cfg.Source
+	if source == "" {
+		source = "defaults"
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK\n", source)
+		return exitConfigOK
+	}
+
+	fmt.Printf("%s: %d problem(s)\n", source, len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  - %s: %s\n", problem.Path, problem.Message)
+		if problem.Hint != "" {
+			fmt.Printf("    hint: %s\n", problem.Hint)
+		}
+	}
+	return exitConfigInvalid
+}
+
+func configShow(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	redact := fs.Bool("redact", false, "mask secrets")
+	fs.Parse(args)
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return exitConfigError
+	}
+	if *redact {
+		cfg = cfg.Redacted()
+	}
+
+	if cfg.Source != "" {
+		fmt.Printf("# Effective config loaded from %s\n", cfg.Source)
+	}
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode config: %v\n", err)
+		return exitConfigError
+	}
+	annotateSources(&doc, "", cfg)
+	data, err := yaml.Marshal(&doc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode config: %v\n", err)
+		return exitConfigError
+	}
+	os.Stdout.Write(data)
+	return exitConfigOK
+}
+
+// annotateSources adds a line comment to every leaf key saying whether its
+// value came from the environment, the file or the defaults
+func annotateSources(node *yaml.Node, prefix string, cfg *config.Config) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if value.Kind == yaml.MappingNode {
+			annotateSources(value, path, cfg)
+			continue
+		}
+		key.LineComment = cfg.ValueSource(path)
+	}
+}
+
+// configSet changes one field in place. Values use the same text as the
+// TUI form: one-line YAML for lists, or comma separated simple strings.
+// It exits 1 without writing when the new value is invalid.
+func configSet(args []string) int {
+	fs := flag.NewFlagSet("config set", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	intoInclude := fs.Bool("include", false, "write a value set by an include back to that include")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return exitConfigError
+	}
+	key, value := fs.Arg(0), fs.Arg(1)
+
+	cfg, err := config.LoadRaw(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return exitConfigError
+	}
+	if cfg.Source == "" {
+		fmt.Fprintln(os.Stderr, "no config file found; pass -config")
+		return exitConfigError
+	}
+	if err := cfg.SetFieldText(key, value); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfigInvalid
+	}
+
+	// Problems elsewhere in the file shouldn't block fixing them one by one
+	if err := cfg.Validate(); err != nil {
+		var verr *config.ValidationError
+		if !errors.As(err, &verr) {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitConfigInvalid
+		}
+		invalid := false
+		for _, problem := range verr.Problems {
+			if problem.Path == key || strings.HasPrefix(problem.Path, key+".") || strings.HasPrefix(problem.Path, key+"[") {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", problem.Path, problem.Message)
+				invalid = true
+			}
+		}
+		if invalid {
+			return exitConfigInvalid
+		}
+	}
+
+	save := config.Save
+	if *intoInclude {
+		save = config.SaveIncludes
+	}
+	if err := save(cfg.Source, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save config: %v\n", err)
+		return exitConfigError
+	}
+	return exitConfigOK
+}
+
+// configMigrate upgrades an older config file in place, keeping a backup
+func configMigrate(args []string) int {
+	fs := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	fs.Parse(args)
+
+	cfg, err := config.LoadRaw(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return exitConfigError
+	}
+	if cfg.Source == "" {
+		fmt.Fprintln(os.Stderr, "no config file found; pass -config")
+		return exitConfigError
+	}
+
+	notes, err := config.Migrate(cfg.Source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to migrate config: %v\n", err)
+		return exitConfigError
+	}
+	if len(notes) == 0 {
+		fmt.Printf("%s is already at version %d\n", cfg.Source, config.CurrentVersion)
+		return exitConfigOK
+	}
+	for _, note := range notes {
+		fmt.Println(note)
+	}
+	return exitConfigOK
+}
+
+// configDiff compares two files after defaults are applied. Like diff(1),
+// it exits 0 when they match and 1 when they differ. Secrets are masked.
+func configDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return exitConfigError
+	}
+
+	a, err := config.LoadRaw(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load %s: %v\n", args[0], err)
+		return exitConfigError
+	}
+	b, err := config.LoadRaw(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load %s: %v\n", args[1], err)
+		return exitConfigError
+	}
+	// Compare real values so changed secrets show up, but print them masked
+	paths := config.Diff(a, b)
+	if len(paths) == 0 {
+		return exitConfigOK
+	}
+	a, b = a.Redacted(), b.Redacted()
+
+	fmt.Printf("--- %s\n+++ %s\n", args[0], args[1])
+	for _, path := range paths {
+		before, _ := a.Value(path)
+		after, _ := b.Value(path)
+		fmt.Printf("%s:\n  - %s\n  + %s\n", path, formatConfigValue(before), formatConfigValue(after))
+	}
+	return exitConfigInvalid
+}
+
+func configSchema() int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(config.JSONSchema()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode schema: %v\n", err)
+		return exitConfigError
+	}
+	return exitConfigOK
+}
+
+// formatConfigValue renders a value on one line for diff output, using
+// the yaml key names for structs
+func formatConfigValue(v any) string {
+	var plain any
+	if data, err := yaml.Marshal(v); err == nil && yaml.Unmarshal(data, &plain) == nil {
+		v = plain
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
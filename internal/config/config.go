@@ -2,76 +2,361 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config represents the full configuration structure
 type Config struct {
-	GitHub   GitHubConfig   `mapstructure:"github" yaml:"github"`
-	VM       VMConfig       `mapstructure:"vm" yaml:"vm"`
+	// Schema version; older files are migrated on load
+	Version int `mapstructure:"version" yaml:"version" form:"-"`
+	// Fail to load when the file has unknown keys instead of warning
+	Strict bool `mapstructure:"strict" yaml:"strict,omitempty" help:"Fail to load when the file has unknown keys"`
+	// Files merged underneath this one, relative to it; see layerFiles
+	Include  []string       `mapstructure:"include" yaml:"include,omitempty" single:"true" help:"Base files merged underneath this one (comma separated)"`
+	GitHub   GitHubConfig   `mapstructure:"github" yaml:"github" label:"GitHub"`
+	VM       VMConfig       `mapstructure:"vm" yaml:"vm" label:"VM"`
 	Registry RegistryConfig `mapstructure:"registry" yaml:"registry"`
 	Options  OptionsConfig  `mapstructure:"options" yaml:"options"`
 	Daemon   DaemonConfig   `mapstructure:"daemon" yaml:"daemon"`
-	PostHog  PostHogConfig  `mapstructure:"posthog" yaml:"posthog"`
+	PostHog  PostHogConfig  `mapstructure:"posthog" yaml:"posthog" label:"PostHog"`
+
+	// Source is the file the config was loaded from, if any
+	Source string `mapstructure:"-" yaml:"-"`
+	// Layers are the files merged into the config, lowest precedence first
+	Layers []string `mapstructure:"-" yaml:"-"`
+	// Warnings are non-fatal problems found while loading, such as unknown
+	// keys or a performed migration
+	Warnings []string `mapstructure:"-" yaml:"-"`
+
+	// sources maps each config path to where its value came from
+	sources map[string]string
+
+	// secretRefs maps secret field paths to the references they were
+	// resolved from
+	secretRefs map[string]string
 }
 
 // GitHubConfig contains GitHub API and runner settings
 type GitHubConfig struct {
-	APIToken             string   `mapstructure:"api_token" yaml:"api_token"`
-	RegistrationEndpoint string   `mapstructure:"registration_endpoint" yaml:"registration_endpoint"`
-	RunnerURL            string   `mapstructure:"runner_url" yaml:"runner_url"`
-	RunnerName           string   `mapstructure:"runner_name" yaml:"runner_name"`
-	RunnerLabels         []string `mapstructure:"runner_labels" yaml:"runner_labels"`
-	RunnerGroup          string   `mapstructure:"runner_group" yaml:"runner_group"`
+	APIToken             string   `mapstructure:"api_token" yaml:"api_token" label:"GitHub API token" required:"true" secret:"true" help:"Personal access token with repo or admin:org scope"`
+	RegistrationEndpoint string   `mapstructure:"registration_endpoint" yaml:"registration_endpoint" label:"Registration endpoint" required:"true"`
+	RunnerURL            string   `mapstructure:"runner_url" yaml:"runner_url" label:"Runner URL" required:"true"`
+	RunnerName           string   `mapstructure:"runner_name" yaml:"runner_name" label:"Runner name"`
+	RunnerLabels         []string `mapstructure:"runner_labels" yaml:"runner_labels" label:"Runner labels" help:"Comma separated, e.g. self-hosted,arm64"`
+	RunnerGroup          string   `mapstructure:"runner_group" yaml:"runner_group" label:"Runner group" help:"Optional"`
+	// Go template for the name registered with GitHub; see RunnerNameData
+	RunnerNameTemplate string `mapstructure:"runner_name_template" yaml:"runner_name_template" label:"Runner name template" help:"Go template; fields .Pool .HostID .Slot .Suffix"`
 }
 
-// VMConfig contains VM credentials
+// DefaultRunnerNameTemplate keeps runner names unique across hosts and runs
+const DefaultRunnerNameTemplate = "{{.Pool}}-{{.HostID}}-{{.Slot}}-{{.Suffix}}"
+
+// VMConfig contains VM credentials and per-clone runtime settings
 type VMConfig struct {
-	Username string `mapstructure:"username" yaml:"username"`
-	Password string `mapstructure:"password" yaml:"password"`
-	Display  string `mapstructure:"display" yaml:"display"`
+	Username string `mapstructure:"username" yaml:"username" label:"VM username" required:"true"`
+	Password string `mapstructure:"password" yaml:"password" label:"VM password" required:"true" secret:"true"`
+	Display  string `mapstructure:"display" yaml:"display" label:"VM display" help:"WIDTHxHEIGHT, e.g. 3840x2160"`
+	// Resource overrides applied with `tart set` after cloning; 0 keeps the image value
+	CPU        int              `mapstructure:"cpu" yaml:"cpu,omitempty" label:"CPUs" help:"0 keeps the image value"`
+	MemoryMB   int              `mapstructure:"memory_mb" yaml:"memory_mb,omitempty" label:"Memory (MB)" help:"0 keeps the image value"`
+	DiskSizeGB int              `mapstructure:"disk_size_gb" yaml:"disk_size_gb,omitempty" label:"Disk size (GB)" help:"0 keeps the image value"`
+	Bootstrap  []BootstrapStep  `mapstructure:"bootstrap" yaml:"bootstrap,omitempty" help:"YAML list of {name, script | file, env}"`
+	Boot       BootConfig       `mapstructure:"boot" yaml:"boot"`
+	Readiness  []ReadinessProbe `mapstructure:"readiness_probes" yaml:"readiness_probes,omitempty" help:"YAML list of {name, type, target, command}"`
+	Mounts     []MountConfig    `mapstructure:"mounts" yaml:"mounts,omitempty" help:"YAML list of {name, host_path, read_only, per_slot}"`
+	Network    NetworkConfig    `mapstructure:"network" yaml:"network"`
+	Snapshot   SnapshotConfig   `mapstructure:"snapshot" yaml:"snapshot"`
+}
+
+// SnapshotConfig enables resuming runner VMs from a suspended snapshot of the
+// booted image instead of cold booting every clone
+type SnapshotConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	// How long a resumed clone may take to report an IP before falling back to a cold boot
+	ResumeTimeout string `mapstructure:"resume_timeout" yaml:"resume_timeout"`
+}
+
+// DefaultResumeTimeout bounds how long a snapshot resume may take
+const DefaultResumeTimeout = 1 * time.Minute
+
+// ResumeTimeoutDuration returns how long to wait for a resumed clone
+func (s SnapshotConfig) ResumeTimeoutDuration() time.Duration {
+	return ParseDurationOr(s.ResumeTimeout, DefaultResumeTimeout)
+}
+
+// Network modes
+const (
+	NetworkShared  = "shared"
+	NetworkSoftnet = "softnet"
+	NetworkBridged = "bridged"
+)
+
+// NetworkConfig selects how the guest is attached to the network.
+// "shared" is Tart's default NAT; "softnet" isolates the guest from the host
+// and private networks except for the CIDRs in Allow; "bridged" attaches the
+// guest directly to Interface.
+type NetworkConfig struct {
+	Mode      string   `mapstructure:"mode" yaml:"mode" enum:"shared,softnet,bridged"`
+	Allow     []string `mapstructure:"allow" yaml:"allow,omitempty" help:"CIDRs reachable in softnet mode, comma separated"`
+	Interface string   `mapstructure:"interface" yaml:"interface,omitempty" help:"Host interface for bridged mode, e.g. en0"`
+}
+
+// MountConfig shares a host directory with the guest via `tart run --dir`.
+// The directory appears in the guest under "/Volumes/My Shared Files/<name>".
+type MountConfig struct {
+	Name     string `mapstructure:"name" yaml:"name"`
+	HostPath string `mapstructure:"host_path" yaml:"host_path"`
+	ReadOnly bool   `mapstructure:"read_only" yaml:"read_only,omitempty"`
+	// PerSlot mounts "<host_path>/slot-<N>" so concurrent VMs don't share a directory
+	PerSlot bool `mapstructure:"per_slot" yaml:"per_slot,omitempty"`
+}
+
+// BootConfig contains per-stage timeouts and poll intervals for VM startup.
+// Values are Go durations (e.g. "5m", "1s").
+type BootConfig struct {
+	IPTimeout         string `mapstructure:"ip_timeout" yaml:"ip_timeout" label:"IP timeout"`
+	IPPollInterval    string `mapstructure:"ip_poll_interval" yaml:"ip_poll_interval" label:"IP poll interval"`
+	SSHTimeout        string `mapstructure:"ssh_timeout" yaml:"ssh_timeout" label:"SSH timeout"`
+	SSHPollInterval   string `mapstructure:"ssh_poll_interval" yaml:"ssh_poll_interval" label:"SSH poll interval"`
+	ProbeTimeout      string `mapstructure:"probe_timeout" yaml:"probe_timeout"`
+	ProbePollInterval string `mapstructure:"probe_poll_interval" yaml:"probe_poll_interval"`
+}
+
+// Boot stage defaults
+const (
+	DefaultIPTimeout         = 5 * time.Minute
+	DefaultIPPollInterval    = 1 * time.Second
+	DefaultSSHTimeout        = 5 * time.Minute
+	DefaultSSHPollInterval   = 1 * time.Second
+	DefaultProbeTimeout      = 2 * time.Minute
+	DefaultProbePollInterval = 2 * time.Second
+)
+
+// IPTimeoutDuration returns how long to wait for the VM to get an IP
+func (b BootConfig) IPTimeoutDuration() time.Duration {
+	return ParseDurationOr(b.IPTimeout, DefaultIPTimeout)
+}
+
+// IPPollIntervalDuration returns how often to poll for the VM IP
+func (b BootConfig) IPPollIntervalDuration() time.Duration {
+	return ParseDurationOr(b.IPPollInterval, DefaultIPPollInterval)
+}
+
+// SSHTimeoutDuration returns how long to wait for SSH to accept connections
+func (b BootConfig) SSHTimeoutDuration() time.Duration {
+	return ParseDurationOr(b.SSHTimeout, DefaultSSHTimeout)
+}
+
+// SSHPollIntervalDuration returns how often to retry SSH
+func (b BootConfig) SSHPollIntervalDuration() time.Duration {
+	return ParseDurationOr(b.SSHPollInterval, DefaultSSHPollInterval)
+}
+
+// ProbeTimeoutDuration returns how long readiness probes may take to pass
+func (b BootConfig) ProbeTimeoutDuration() time.Duration {
+	return ParseDurationOr(b.ProbeTimeout, DefaultProbeTimeout)
+}
+
+// ProbePollIntervalDuration returns how often failing probes are retried
+func (b BootConfig) ProbePollIntervalDuration() time.Duration {
+	return ParseDurationOr(b.ProbePollInterval, DefaultProbePollInterval)
+}
+
+// Readiness probe types
+const (
+	ProbeCommand      = "command"
+	ProbeRunnerBinary = "runner_binary"
+	ProbeDisk         = "disk"
+	ProbeNetwork      = "network"
+)
+
+// ReadinessProbe is a guest check that must pass before the runner is configured.
+// Type selects a built-in check; Target is its argument (a path for "disk",
+// a URL for "network"). Type "command" runs Command and expects exit status 0.
+type ReadinessProbe struct {
+	Name    string `mapstructure:"name" yaml:"name,omitempty"`
+	Type    string `mapstructure:"type" yaml:"type"`
+	Target  string `mapstructure:"target" yaml:"target,omitempty"`
+	Command string `mapstructure:"command" yaml:"command,omitempty"`
+}
+
+// BootstrapStep is a guest script run over SSH before the runner is configured.
+// Exactly one of Script (inline) or File (host path) must be set.
+type BootstrapStep struct {
+	Name   string   `mapstructure:"name" yaml:"name,omitempty"`
+	Script string   `mapstructure:"script" yaml:"script,omitempty"`
+	File   string   `mapstructure:"file" yaml:"file,omitempty"`
+	Env    []string `mapstructure:"env" yaml:"env,omitempty"`
 }
 
 // RegistryConfig contains OCI registry settings
 type RegistryConfig struct {
-	URL       string `mapstructure:"url" yaml:"url"`
-	ImageName string `mapstructure:"image_name" yaml:"image_name"`
-	Username  string `mapstructure:"username" yaml:"username"`
-	Password  string `mapstructure:"password" yaml:"password"`
+	URL       string `mapstructure:"url" yaml:"url" label:"Registry URL"`
+	ImageName string `mapstructure:"image_name" yaml:"image_name" label:"Registry image name" required:"true"`
+	Username  string `mapstructure:"username" yaml:"username" label:"Registry username"`
+	Password  string `mapstructure:"password" yaml:"password" label:"Registry password" secret:"true" help:"For GHCR, a token with packages:read"`
+	// Number of times a failed pull is retried; downloaded layers are kept
+	PullRetries    int    `mapstructure:"pull_retries" yaml:"pull_retries"`
+	PullRetryDelay string `mapstructure:"pull_retry_delay" yaml:"pull_retry_delay"`
+}
+
+// Reference returns the full image reference, prefixing image_name with the
+// registry host unless it already includes it
+func (r RegistryConfig) Reference() string {
+	if r.URL == "" || strings.HasPrefix(r.ImageName, r.URL+"/") {
+		return r.ImageName
+	}
+	return r.URL + "/" + r.ImageName
+}
+
+// DefaultPullRetryDelay is the wait between pull attempts
+const DefaultPullRetryDelay = 30 * time.Second
+
+// PullRetryDelayDuration returns the wait between pull attempts
+func (r RegistryConfig) PullRetryDelayDuration() time.Duration {
+	return ParseDurationOr(r.PullRetryDelay, DefaultPullRetryDelay)
 }
 
 // OptionsConfig contains runtime options
 type OptionsConfig struct {
-	TruncateSize         string `mapstructure:"truncate_size" yaml:"truncate_size"`
-	LogFile              string `mapstructure:"log_file" yaml:"log_file"`
+	TruncateSize         string `mapstructure:"truncate_size" yaml:"truncate_size" help:"Disk size for clones, e.g. 50G"`
+	LogFile              string `mapstructure:"log_file" yaml:"log_file" help:"Relative to the working directory; empty disables it"`
 	ShutdownFlagFile     string `mapstructure:"shutdown_flag_file" yaml:"shutdown_flag_file"`
-	WorkingDirectory     string `mapstructure:"working_directory" yaml:"working_directory"`
+	WorkingDirectory     string `mapstructure:"working_directory" yaml:"working_directory" required:"true" help:"Absolute path"`
 	MaxConcurrentRunners int    `mapstructure:"max_concurrent_runners" yaml:"max_concurrent_runners"`
+	// Longest a runner may run before its VM is torn down; empty means no limit
+	JobTimeout string `mapstructure:"job_timeout" yaml:"job_timeout,omitempty" help:"Go duration, e.g. 6h; empty means no limit"`
+	// Identifies this host in runner names; derived from the hostname and
+	// persisted in working_directory when empty
+	HostID      string            `mapstructure:"host_id" yaml:"host_id" label:"Host ID" help:"Derived from the hostname when empty"`
+	Hooks       HooksConfig       `mapstructure:"hooks" yaml:"hooks"`
+	Diagnostics DiagnosticsConfig `mapstructure:"diagnostics" yaml:"diagnostics"`
+	WarmPool    WarmPoolConfig    `mapstructure:"warm_pool" yaml:"warm_pool"`
+	// Concurrency overrides for recurring time windows; the first active
+	// window wins
+	Schedule []ScheduleWindow `mapstructure:"schedule" yaml:"schedule,omitempty" help:"YAML list of {name, days, start, end, cron, duration, timezone, max_concurrent_runners}"`
+	// Unix socket for runtime controls such as `rvmm concurrency`; relative
+	// paths are resolved against working_directory, empty disables it
+	ControlSocket string `mapstructure:"control_socket" yaml:"control_socket" help:"Empty disables rvmm concurrency"`
+	// Reload the config when its file changes, in addition to SIGHUP
+	WatchConfig bool `mapstructure:"watch_config" yaml:"watch_config"`
+}
+
+// JobTimeoutDuration returns how long a runner may run, or 0 for no limit
+func (o OptionsConfig) JobTimeoutDuration() time.Duration {
+	return ParseDurationOr(o.JobTimeout, 0)
+}
+
+// WarmPoolConfig keeps VMs cloned, booted and SSH-ready ahead of demand
+type WarmPoolConfig struct {
+	Size int `mapstructure:"size" yaml:"size"`
+	// Warm VMs older than this are recycled
+	MaxAge string `mapstructure:"max_age" yaml:"max_age" help:"Go duration, e.g. 2h"`
+}
+
+// DefaultWarmMaxAge is how long a warm VM may wait before it is recycled
+const DefaultWarmMaxAge = 2 * time.Hour
+
+// MaxAgeDuration returns how long a warm VM may wait for a slot
+func (w WarmPoolConfig) MaxAgeDuration() time.Duration {
+	return ParseDurationOr(w.MaxAge, DefaultWarmMaxAge)
+}
+
+// DiagnosticsConfig controls the failure diagnostics bundle
+type DiagnosticsConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	// Directory for bundles; relative paths are resolved against working_directory
+	Directory string `mapstructure:"directory" yaml:"directory"`
+	// How much guest system log to include, as a `log show --last` duration (e.g. "15m")
+	SystemLogWindow string `mapstructure:"system_log_window" yaml:"system_log_window" help:"Go duration, e.g. 15m"`
+}
+
+// HooksConfig contains host-side executables run at runner lifecycle points
+type HooksConfig struct {
+	AfterClone   []HookConfig `mapstructure:"after_clone" yaml:"after_clone,omitempty" help:"YAML list of {command, args, timeout}"`
+	AfterIP      []HookConfig `mapstructure:"after_ip" yaml:"after_ip,omitempty" label:"After IP" help:"YAML list of {command, args, timeout}"`
+	BeforeRunner []HookConfig `mapstructure:"before_runner" yaml:"before_runner,omitempty" help:"YAML list of {command, args, timeout}"`
+	AfterJob     []HookConfig `mapstructure:"after_job" yaml:"after_job,omitempty" help:"YAML list of {command, args, timeout}"`
+	OnFailure    []HookConfig `mapstructure:"on_failure" yaml:"on_failure,omitempty" help:"YAML list of {command, args, timeout}"`
+}
+
+// HookConfig describes a single hook executable
+type HookConfig struct {
+	Command string   `mapstructure:"command" yaml:"command"`
+	Args    []string `mapstructure:"args" yaml:"args,omitempty"`
+	Timeout string   `mapstructure:"timeout" yaml:"timeout,omitempty"`
+}
+
+// DefaultHookTimeout is used when a hook does not specify a timeout
+const DefaultHookTimeout = 60 * time.Second
+
+// TimeoutDuration returns the parsed hook timeout, falling back to the default
+func (h HookConfig) TimeoutDuration() time.Duration {
+	return ParseDurationOr(h.Timeout, DefaultHookTimeout)
+}
+
+// ParseDurationOr parses a positive duration, returning def when the value
+// is empty or invalid
+func ParseDurationOr(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
 }
 
-// DaemonConfig contains LaunchDaemon settings
+// DaemonConfig contains background service settings
 type DaemonConfig struct {
-	Label     string `mapstructure:"label" yaml:"label"`
-	PlistPath string `mapstructure:"plist_path" yaml:"plist_path"`
-	User      string `mapstructure:"user" yaml:"user"`
+	Label     string `mapstructure:"label" yaml:"label" label:"Daemon label" help:"Reverse-DNS name, e.g. com.example.rvmm"`
+	PlistPath string `mapstructure:"plist_path" yaml:"plist_path" label:"Daemon plist path"`
+	User      string `mapstructure:"user" yaml:"user" label:"Daemon user"`
+	// Service manager: launchd or systemd; empty picks the one native to the OS
+	Manager string `mapstructure:"manager" yaml:"manager,omitempty" enum:",launchd,systemd" help:"Empty picks launchd on macOS and systemd on Linux"`
+	// Directory for systemd units
+	UnitDir string `mapstructure:"unit_dir" yaml:"unit_dir" label:"systemd unit directory"`
 }
 
 // PostHogConfig contains PostHog analytics settings
 type PostHogConfig struct {
 	Enabled      bool   `mapstructure:"enabled" yaml:"enabled"`
-	APIKey       string `mapstructure:"api_key" yaml:"api_key"`
+	APIKey       string `mapstructure:"api_key" yaml:"api_key" label:"API key" secret:"true"`
 	Host         string `mapstructure:"host" yaml:"host"`
-	MachineLabel string `mapstructure:"machine_label" yaml:"machine_label"`
+	MachineLabel string `mapstructure:"machine_label" yaml:"machine_label" help:"Identifies this machine in PostHog"`
 }
 
-// Load reads configuration from file with defaults
+// Load reads configuration from file with defaults and resolves secret
+// references
 func Load(configPath string) (*Config, error) {
+	cfg, err := LoadRaw(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, fmt.Errorf("error resolving secret: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadRaw reads configuration like Load but leaves secret references
+// unresolved, for editing the config without exposing secrets
+func LoadRaw(configPath string) (*Config, error) {
 	v := viper.New()
 
 	// Set defaults
 	setDefaults(v)
 
+	// RVMM_* environment variables override the file and defaults
+	v.AllowEmptyEnv(true)
+	if err := bindEnv(v); err != nil {
+		return nil, fmt.Errorf("error reading environment: %w", err)
+	}
+
 	// Set config file
 	if configPath != "" {
 		v.SetConfigFile(configPath)
@@ -90,35 +375,94 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	// Merge includes and overlays, upgrade older layouts and look for
+	// misspelled keys
+	var layers []layer
+	var warnings, unknown []string
+	if path := v.ConfigFileUsed(); path != "" {
+		var err error
+		if layers, warnings, unknown, err = readLayers(v, path); err != nil {
+			return nil, err
+		}
+	}
+
 	// Unmarshal
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("error parsing config: %w", err)
 	}
+	cfg.Source = v.ConfigFileUsed()
+	for _, l := range layers {
+		cfg.Layers = append(cfg.Layers, l.path)
+	}
+	defaults := viper.New()
+	setDefaults(defaults)
+	cfg.recordSources(layers, defaults)
+
+	if cfg.Strict && len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown config keys: %s", strings.Join(unknown, "; "))
+	}
+	cfg.Warnings = append(warnings, unknown...)
+	if warning := cfg.runnerNameWarning(); warning != "" {
+		cfg.Warnings = append(cfg.Warnings, warning)
+	}
 
 	return &cfg, nil
 }
 
+// Default returns a config holding only the built-in defaults
+func Default() *Config {
+	v := viper.New()
+	setDefaults(v)
+	var cfg Config
+	// The defaults are plain values of the right types, so this can't fail
+	_ = v.Unmarshal(&cfg)
+	return &cfg
+}
+
 func setDefaults(v *viper.Viper) {
+	v.SetDefault("version", CurrentVersion)
+
 	// VM defaults
 	v.SetDefault("vm.username", "admin")
 	v.SetDefault("vm.password", "admin")
 	v.SetDefault("vm.display", "3840x2160")
+	v.SetDefault("vm.network.mode", "shared")
+	v.SetDefault("vm.snapshot.enabled", false)
+	v.SetDefault("vm.snapshot.resume_timeout", "1m")
+	v.SetDefault("vm.boot.ip_timeout", "5m")
+	v.SetDefault("vm.boot.ip_poll_interval", "1s")
+	v.SetDefault("vm.boot.ssh_timeout", "5m")
+	v.SetDefault("vm.boot.ssh_poll_interval", "1s")
+	v.SetDefault("vm.boot.probe_timeout", "2m")
+	v.SetDefault("vm.boot.probe_poll_interval", "2s")
 
 	// GitHub defaults
 	v.SetDefault("github.runner_name", "runner")
 	v.SetDefault("github.runner_labels", []string{"self-hosted", "arm64"})
+	v.SetDefault("github.runner_name_template", DefaultRunnerNameTemplate)
+
+	// Registry defaults
+	v.SetDefault("registry.pull_retries", 3)
+	v.SetDefault("registry.pull_retry_delay", "30s")
 
 	// Options defaults
 	v.SetDefault("options.log_file", "runner.log")
 	v.SetDefault("options.shutdown_flag_file", ".shutdown")
 	v.SetDefault("options.working_directory", "/Users/admin/vm")
 	v.SetDefault("options.max_concurrent_runners", 1)
+	v.SetDefault("options.control_socket", "control.sock")
+	v.SetDefault("options.warm_pool.size", 0)
+	v.SetDefault("options.warm_pool.max_age", "2h")
+	v.SetDefault("options.diagnostics.enabled", false)
+	v.SetDefault("options.diagnostics.directory", "diagnostics")
+	v.SetDefault("options.diagnostics.system_log_window", "15m")
 
 	// Daemon defaults
 	v.SetDefault("daemon.label", "com.mirego.ekiden")
 	v.SetDefault("daemon.plist_path", "/Library/LaunchDaemons/com.mirego.ekiden.plist")
 	v.SetDefault("daemon.user", "admin")
+	v.SetDefault("daemon.unit_dir", "/etc/systemd/system")
 
 	// PostHog defaults
 	v.SetDefault("posthog.enabled", false)
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Diff returns the yaml paths of the fields that differ between a and b,
+// e.g. "github.runner_labels". Lists are compared as a whole.
+func Diff(a, b *Config) []string {
+	var paths []string
+	diffValue(reflect.ValueOf(*a), reflect.ValueOf(*b), "", &paths)
+	return paths
+}
+
+// CopyField sets the field at a yaml path (as returned by Diff) to its
+// value in from
+func (c *Config) CopyField(from *Config, path string) error {
+	dst, err := fieldByPath(reflect.ValueOf(c).Elem(), path)
+	if err != nil {
+		return err
+	}
+	src, err := fieldByPath(reflect.ValueOf(from).Elem(), path)
+	if err != nil {
+		return err
+	}
+	dst.Set(src)
+	return nil
+}
+
+// Value returns the value of the field at a yaml path
+func (c *Config) Value(path string) (any, error) {
+	v, err := fieldByPath(reflect.ValueOf(c).Elem(), path)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func diffValue(a, b reflect.Value, prefix string, paths *[]string) {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*paths = append(*paths, prefix)
+		}
+		return
+	}
+
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		diffValue(a.Field(i), b.Field(i), path, paths)
+	}
+}
+
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config field %q", path)
+		}
+		found := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if yamlName(t.Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown config field %q", path)
+		}
+	}
+	return v, nil
+}
+
+// yamlName returns the yaml key of a struct field, or "" if it is not
+// serialized
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
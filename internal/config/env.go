@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes environment variables that override config keys, e.g.
+// RVMM_OPTIONS_MAX_CONCURRENT_RUNNERS for options.max_concurrent_runners
+const EnvPrefix = "RVMM"
+
+// Value sources reported by Config.ValueSource
+const (
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+	SourceUnset   = "unset"
+)
+
+// EnvName returns the environment variable that overrides a config path
+func EnvName(path string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// configKey is a settable config path and whether it holds a list of objects
+type configKey struct {
+	path    string
+	objects bool
+}
+
+// configKeys lists every leaf config path. Lists of objects are leaves
+// since their elements have no fixed paths.
+func configKeys() []configKey {
+	var keys []configKey
+	walkKeys(reflect.TypeOf(Config{}), "", &keys)
+	return keys
+}
+
+func walkKeys(t reflect.Type, prefix string, keys *[]configKey) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			walkKeys(f.Type, path, keys)
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+			*keys = append(*keys, configKey{path: path, objects: true})
+		default:
+			*keys = append(*keys, configKey{path: path})
+		}
+	}
+}
+
+// bindEnv makes every config key overridable from the environment. Lists
+// of strings are comma separated; lists of objects are YAML or JSON.
+func bindEnv(v *viper.Viper) error {
+	for _, key := range configKeys() {
+		// Includes are followed while reading files, before env applies
+		if key.path == "include" {
+			continue
+		}
+		name := EnvName(key.path)
+		if !key.objects {
+			if err := v.BindEnv(key.path, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		var list []any
+		if err := yaml.Unmarshal([]byte(value), &list); err != nil {
+			return fmt.Errorf("%s must be a YAML or JSON list: %w", name, err)
+		}
+		v.Set(key.path, list)
+	}
+	return nil
+}
+
+// recordSources notes where each key's value came from. layers are the
+// merged files, lowest precedence first; defaults holds only the built-in
+// defaults. File sources name the file when more than one was merged.
+func (c *Config) recordSources(layers []layer, defaults *viper.Viper) {
+	c.sources = make(map[string]string)
+	for _, key := range configKeys() {
+		name := EnvName(key.path)
+		if envSet(name) {
+			c.sources[key.path] = SourceEnv + " " + name
+			continue
+		}
+
+		source := ""
+		for i := len(layers) - 1; i >= 0; i-- {
+			if layers[i].v.InConfig(key.path) {
+				source = SourceFile
+				if len(layers) > 1 {
+					source += " " + layers[i].path
+				}
+				break
+			}
+		}
+		switch {
+		case source != "":
+			c.sources[key.path] = source
+		case defaults.IsSet(key.path):
+			c.sources[key.path] = SourceDefault
+		default:
+			c.sources[key.path] = SourceUnset
+		}
+	}
+}
+
+// ValueSource reports where the value of a config path came from:
+// "env NAME", "file", "default" or "unset"
+func (c *Config) ValueSource(path string) string {
+	if source, ok := c.sources[path]; ok {
+		return source
+	}
+	return SourceUnset
+}
+
+func envSet(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
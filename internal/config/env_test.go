@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeEnvConfig writes a config that sets a few keys the tests override
+func writeEnvConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rvmm.yaml")
+	data := `version: 2
+github:
+  runner_group: file-group
+  runner_labels: [file-label]
+options:
+  max_concurrent_runners: 2
+  schedule:
+    - name: file-window
+      start: "08:00"
+      end: "18:00"
+      max_concurrent_runners: 1
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"options.max_concurrent_runners": "RVMM_OPTIONS_MAX_CONCURRENT_RUNNERS",
+		"github.api_token":               "RVMM_GITHUB_API_TOKEN",
+		"vm.boot.ssh_timeout":            "RVMM_VM_BOOT_SSH_TIMEOUT",
+	}
+	for path, want := range tests {
+		if got := EnvName(path); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	path := writeEnvConfig(t)
+	t.Setenv("RVMM_OPTIONS_MAX_CONCURRENT_RUNNERS", "4")
+	t.Setenv("RVMM_GITHUB_RUNNER_GROUP", "env-group")
+	t.Setenv("RVMM_VM_DISPLAY", "1920x1080")
+	t.Setenv("RVMM_GITHUB_RUNNER_LABELS", "macos,arm64")
+	t.Setenv("RVMM_OPTIONS_SCHEDULE", `[{name: env-window, start: "22:00", end: "06:00", max_concurrent_runners: 3}]`)
+	t.Setenv("RVMM_VM_MOUNTS", `[{"name": "cache", "host_path": "/tmp/cache", "per_slot": true}]`)
+
+	cfg, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Options.MaxConcurrentRunners != 4 {
+		t.Errorf("max_concurrent_runners = %d, want the env value 4", cfg.Options.MaxConcurrentRunners)
+	}
+	if cfg.GitHub.RunnerGroup != "env-group" {
+		t.Errorf("runner_group = %q, want the env value", cfg.GitHub.RunnerGroup)
+	}
+	if cfg.VM.Display != "1920x1080" {
+		t.Errorf("display = %q, want the env value for a key missing from the file", cfg.VM.Display)
+	}
+	if got := strings.Join(cfg.GitHub.RunnerLabels, " "); got != "macos arm64" {
+		t.Errorf("runner_labels = %q, want the comma separated env list", got)
+	}
+	if len(cfg.Options.Schedule) != 1 || cfg.Options.Schedule[0].Name != "env-window" || cfg.Options.Schedule[0].MaxConcurrentRunners != 3 {
+		t.Errorf("schedule = %+v, want the env list replacing the file's", cfg.Options.Schedule)
+	}
+	if len(cfg.VM.Mounts) != 1 || cfg.VM.Mounts[0].HostPath != "/tmp/cache" || !cfg.VM.Mounts[0].PerSlot {
+		t.Errorf("mounts = %+v, want the JSON env list", cfg.VM.Mounts)
+	}
+}
+
+func TestEnvInvalidObjectList(t *testing.T) {
+	path := writeEnvConfig(t)
+	t.Setenv("RVMM_OPTIONS_SCHEDULE", `{name: not-a-list}`)
+	if _, err := LoadRaw(path); err == nil || !strings.Contains(err.Error(), "RVMM_OPTIONS_SCHEDULE") {
+		t.Errorf("LoadRaw error = %v, want it to name RVMM_OPTIONS_SCHEDULE", err)
+	}
+}
+
+func TestValueSource(t *testing.T) {
+	path := writeEnvConfig(t)
+	t.Setenv("RVMM_OPTIONS_MAX_CONCURRENT_RUNNERS", "4")
+	t.Setenv("RVMM_GITHUB_RUNNER_LABELS", "macos")
+
+	cfg, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"options.max_concurrent_runners": "env RVMM_OPTIONS_MAX_CONCURRENT_RUNNERS",
+		"github.runner_labels":           "env RVMM_GITHUB_RUNNER_LABELS",
+		"github.runner_group":            SourceFile,
+		"options.schedule":               SourceFile,
+		"github.runner_name_template":    SourceDefault,
+		"github.api_token":               SourceUnset,
+		"no.such.key":                    SourceUnset,
+	}
+	for key, want := range tests {
+		if got := cfg.ValueSource(key); got != want {
+			t.Errorf("ValueSource(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestValueSourceNamesLayer(t *testing.T) {
+	dir, _ := writeLayers(t)
+	cfg, err := LoadRaw(filepath.Join(dir, "rvmm.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"github.runner_group":            filepath.Join(dir, "base.yaml"),
+		"github.api_token":               filepath.Join(dir, "rvmm.yaml"),
+		"options.max_concurrent_runners": filepath.Join(dir, "rvmm.d/10-pool.yaml"),
+		"vm.display":                     filepath.Join(dir, "pool.yaml"),
+	}
+	for key, file := range tests {
+		if got, want := cfg.ValueSource(key), SourceFile+" "+file; got != want {
+			t.Errorf("ValueSource(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldInfo describes an editable config field. It comes from the struct
+// tags on the config types:
+//
+//	label:"GitHub API token"   display name; defaults to the yaml key
+//	help:"..."                 one-line explanation
+//	required:"true"            must not be empty
+//	secret:"true"              mask the value; it may be a secret reference
+//	enum:"a,b,c"               allowed values
+//	form:"-"                   not shown in editors
+//	single:"true"              a list that may also be written as one value
+type FieldInfo struct {
+	Path string
+	// Section is the label path of the enclosing structs, e.g. "Options / Warm pool"
+	Section  string
+	Label    string
+	Help     string
+	Required bool
+	Secret   bool
+	Enum     []string
+	Kind     FieldKind
+}
+
+// FieldKind selects how a field is edited as text
+type FieldKind int
+
+// Field kinds
+const (
+	FieldString FieldKind = iota
+	FieldInt
+	FieldBool
+	// FieldList is a list of strings, edited as a YAML flow sequence such
+	// as [a, "b,c"]; plain comma separated text is accepted too
+	FieldList
+	// FieldObjects is a list of objects, edited as a YAML flow sequence
+	FieldObjects
+)
+
+// generalSection holds top-level fields that aren't in a section
+const generalSection = "General"
+
+// Fields lists every editable config field in declaration order
+func Fields() []FieldInfo {
+	var fields []FieldInfo
+	walkFields(reflect.TypeOf(Config{}), "", "", &fields)
+	return fields
+}
+
+func walkFields(t reflect.Type, prefix, section string, fields *[]FieldInfo) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" || f.Tag.Get("form") == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		label := fieldLabel(f, name)
+
+		if f.Type.Kind() == reflect.Struct {
+			sub := label
+			if section != "" {
+				sub = section + " / " + label
+			}
+			walkFields(f.Type, path, sub, fields)
+			continue
+		}
+
+		info := FieldInfo{
+			Path:     path,
+			Section:  section,
+			Label:    label,
+			Help:     f.Tag.Get("help"),
+			Required: f.Tag.Get("required") == "true",
+			Secret:   f.Tag.Get("secret") == "true",
+			Kind:     fieldKind(f.Type),
+		}
+		if info.Section == "" {
+			info.Section = generalSection
+		}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			info.Enum = strings.Split(enum, ",")
+		}
+		*fields = append(*fields, info)
+	}
+}
+
+// fieldLabel returns the label tag, or the yaml key in sentence case
+func fieldLabel(f reflect.StructField, name string) string {
+	if label := f.Tag.Get("label"); label != "" {
+		return label
+	}
+	label := strings.ReplaceAll(name, "_", " ")
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+func fieldKind(t reflect.Type) FieldKind {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return FieldInt
+	case reflect.Bool:
+		return FieldBool
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return FieldList
+		}
+		return FieldObjects
+	}
+	return FieldString
+}
+
+// FieldText returns the value at a yaml path as editable text
+func (c *Config) FieldText(path string) (string, error) {
+	field, err := fieldByPath(reflect.ValueOf(c).Elem(), path)
+	if err != nil {
+		return "", err
+	}
+
+	switch fieldKind(field.Type()) {
+	case FieldInt:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case FieldBool:
+		return strconv.FormatBool(field.Bool()), nil
+	case FieldList, FieldObjects:
+		if field.Len() == 0 {
+			return "", nil
+		}
+		var node yaml.Node
+		if err := node.Encode(field.Interface()); err != nil {
+			return "", err
+		}
+		flowStyle(&node)
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return field.String(), nil
+}
+
+// SetFieldText parses text written by FieldText into the field at a yaml
+// path. Text that matches the current value leaves the field untouched, so
+// unedited fields round-trip exactly.
+func (c *Config) SetFieldText(path, text string) error {
+	current, err := c.FieldText(path)
+	if err != nil {
+		return err
+	}
+	if text == current {
+		return nil
+	}
+	field, err := fieldByPath(reflect.ValueOf(c).Elem(), path)
+	if err != nil {
+		return err
+	}
+
+	switch fieldKind(field.Type()) {
+	case FieldInt:
+		if text == "" {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", path)
+		}
+		field.SetInt(n)
+	case FieldBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", path)
+		}
+		field.SetBool(b)
+	case FieldList:
+		var items []string
+		if strings.HasPrefix(strings.TrimSpace(text), "[") {
+			if err := yaml.Unmarshal([]byte(text), &items); err != nil {
+				return fmt.Errorf("%s must be a YAML list of strings: %w", path, err)
+			}
+		} else {
+			for _, item := range strings.Split(text, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case FieldObjects:
+		list := reflect.New(field.Type())
+		if text != "" {
+			if err := yaml.Unmarshal([]byte(text), list.Interface()); err != nil {
+				return fmt.Errorf("%s must be a YAML list: %w", path, err)
+			}
+		}
+		field.Set(list.Elem())
+	default:
+		field.SetString(text)
+	}
+	return nil
+}
+
+// flowStyle renders a node and its children on one line
+func flowStyle(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		flowStyle(child)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// trickyStrings are values that break naive text encodings
+var trickyStrings = []string{
+	`a,b`, ` padded `, `key: value`, `it's "quoted" #not a comment`, `[x]`, `{y}`, `123`, `true`, `null`, ``,
+}
+
+// populate sets every config field under v to a non-default value
+func populate(t *testing.T, v reflect.Value, n *int) {
+	t.Helper()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if yamlName(f) == "" || f.Tag.Get("form") == "-" {
+			continue
+		}
+		populateValue(t, v.Field(i), n)
+	}
+}
+
+func populateValue(t *testing.T, field reflect.Value, n *int) {
+	t.Helper()
+	*n++
+	switch field.Kind() {
+	case reflect.Struct:
+		populate(t, field, n)
+	case reflect.String:
+		field.SetString(trickyStrings[*n%len(trickyStrings)] + "x")
+	case reflect.Int, reflect.Int64:
+		field.SetInt(int64(*n))
+	case reflect.Bool:
+		field.SetBool(!field.Bool())
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			field.Set(reflect.ValueOf(append([]string{}, trickyStrings...)))
+			return
+		}
+		list := reflect.MakeSlice(field.Type(), 2, 2)
+		for j := 0; j < list.Len(); j++ {
+			populateValue(t, list.Index(j), n)
+		}
+		field.Set(list)
+	default:
+		t.Fatalf("populate: unsupported kind %s", field.Kind())
+	}
+}
+
+func TestFieldTextRoundTrip(t *testing.T) {
+	src := Default()
+	n := 0
+	populate(t, reflect.ValueOf(src).Elem(), &n)
+
+	dst := Default()
+	for _, field := range Fields() {
+		text, err := src.FieldText(field.Path)
+		if err != nil {
+			t.Fatalf("FieldText(%s): %v", field.Path, err)
+		}
+		if field.Kind == FieldList || field.Kind == FieldObjects {
+			if strings.Contains(text, "\n") {
+				t.Errorf("FieldText(%s) spans several lines: %q", field.Path, text)
+			}
+		}
+		if err := dst.SetFieldText(field.Path, text); err != nil {
+			t.Fatalf("SetFieldText(%s, %q): %v", field.Path, text, err)
+		}
+	}
+
+	if diff := Diff(src, dst); len(diff) > 0 {
+		for _, path := range diff {
+			want, _ := src.Value(path)
+			got, _ := dst.Value(path)
+			t.Errorf("%s did not round-trip: got %#v, want %#v", path, got, want)
+		}
+	}
+}
+
+func TestSetFieldTextList(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"self-hosted, arm64 ,macOS", []string{"self-hosted", "arm64", "macOS"}},
+		{`[self-hosted, "a,b"]`, []string{"self-hosted", "a,b"}},
+		{" [one]", []string{"one"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.GitHub.RunnerLabels = []string{"old"}
+		if err := cfg.SetFieldText("github.runner_labels", tt.text); err != nil {
+			t.Fatalf("SetFieldText(%q): %v", tt.text, err)
+		}
+		if !reflect.DeepEqual(cfg.GitHub.RunnerLabels, tt.want) {
+			t.Errorf("SetFieldText(%q) = %q, want %q", tt.text, cfg.GitHub.RunnerLabels, tt.want)
+		}
+	}
+
+	cfg := Default()
+	if err := cfg.SetFieldText("github.runner_labels", "[unclosed"); err == nil {
+		t.Error("SetFieldText accepted malformed YAML")
+	}
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth bounds include chains so a mistake can't recurse forever
+const maxIncludeDepth = 8
+
+// layer is one file merged into the config
+type layer struct {
+	path string
+	v    *viper.Viper
+}
+
+// layerFiles returns the files that make up the config at path, lowest
+// precedence first:
+//
+//  1. files named by include, recursively, each before the file including it
+//  2. the config file itself
+//  3. <dir>/<name>.d/*.yaml in lexical order
+//  4. <dir>/<name>.<hostname>.yaml
+//
+// where <name> is the config file name without its extension, e.g.
+// rvmm.d/ and rvmm.<hostname>.yaml next to rvmm.yaml. Overlays may use
+// include too. A file is only merged once.
+func layerFiles(path string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	if err := expandIncludes(path, seen, nil, &files); err != nil {
+		return nil, err
+	}
+
+	overlays, err := overlayFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range overlays {
+		if err := expandIncludes(overlay, seen, nil, &files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// expandIncludes appends the files path includes and then path itself.
+// stack holds the files currently being expanded, to report cycles.
+func expandIncludes(path string, seen map[string]bool, stack []string, files *[]string) error {
+	path = filepath.Clean(path)
+	for _, p := range stack {
+		if p == path {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+		}
+	}
+	if seen[path] {
+		return nil
+	}
+	if len(stack) >= maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d deep", path, maxIncludeDepth)
+	}
+
+	includes, err := readIncludes(path)
+	if err != nil {
+		return err
+	}
+	stack = append(stack, path)
+	for _, include := range includes {
+		include = os.ExpandEnv(include)
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		if err := expandIncludes(include, seen, stack, files); err != nil {
+			return err
+		}
+	}
+
+	seen[path] = true
+	*files = append(*files, path)
+	return nil
+}
+
+// readIncludes returns the include list of a file. include may be a single
+// path or a list of paths.
+func readIncludes(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	var doc struct {
+		Include yaml.Node `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	switch doc.Include.Kind {
+	case 0:
+		return nil, nil
+	case yaml.ScalarNode:
+		if doc.Include.Value == "" {
+			return nil, nil
+		}
+		return []string{doc.Include.Value}, nil
+	default:
+		var includes []string
+		if err := doc.Include.Decode(&includes); err != nil {
+			return nil, fmt.Errorf("%s: include must be a path or a list of paths", path)
+		}
+		return includes, nil
+	}
+}
+
+// overlayFiles returns the overlay directory files and host overlays for
+// the config at path that exist
+func overlayFiles(path string) ([]string, error) {
+	dir, hostFiles := overlayPaths(path)
+
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, hostFile := range hostFiles {
+		if _, err := os.Stat(hostFile); err == nil {
+			files = append(files, hostFile)
+		}
+	}
+	return files, nil
+}
+
+// overlayPaths returns the overlay directory and the host overlay files for
+// the config at path. Both the short and the full hostname are tried, short
+// first, so mac-07.local matches rvmm.mac-07.yaml and rvmm.mac-07.local.yaml.
+func overlayPaths(path string) (string, []string) {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	dir := filepath.Join(filepath.Dir(path), name+".d")
+
+	var hostFiles []string
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hostname = strings.ToLower(hostname)
+		short, _, _ := strings.Cut(hostname, ".")
+		hostFiles = append(hostFiles, filepath.Join(filepath.Dir(path), name+"."+short+".yaml"))
+		if short != hostname {
+			hostFiles = append(hostFiles, filepath.Join(filepath.Dir(path), name+"."+hostname+".yaml"))
+		}
+	}
+	return dir, hostFiles
+}
+
+// readLayers deep-merges the layers of the config at path into v. Maps are
+// merged key by key; scalars and lists in later layers replace earlier ones.
+// Older layers are upgraded in memory; the files are left untouched. It
+// returns the layers, notes about migrations and any unknown keys.
+func readLayers(v *viper.Viper, path string) ([]layer, []string, []string, error) {
+	files, err := layerFiles(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	path = filepath.Clean(path)
+
+	var layers []layer
+	var notes, unknown []string
+	v.SetConfigType("yaml")
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error reading config: %w", err)
+		}
+
+		migrated, version, _, err := migrateLayer(file, path, data)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if migrated != nil {
+			notes = append(notes, fmt.Sprintf("%s uses config version %d; upgraded in memory, run `rvmm config migrate` to update the file", file, version))
+			data = migrated
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		root := documentRoot(&doc)
+
+		if root != nil {
+			for _, key := range unknownKeys(root) {
+				if len(files) > 1 {
+					key = file + ": " + key
+				}
+				unknown = append(unknown, key)
+			}
+		}
+
+		lv := viper.New()
+		lv.SetConfigType("yaml")
+		if err := lv.ReadConfig(bytes.NewReader(data)); err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		layers = append(layers, layer{path: file, v: lv})
+
+		if i == 0 {
+			err = v.ReadConfig(bytes.NewReader(data))
+		} else {
+			err = v.MergeConfig(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+	}
+	return layers, notes, unknown, nil
+}
+
+// WatchDirs returns the directories holding files that make up the config
+func (c *Config) WatchDirs() []string {
+	if c.Source == "" {
+		return nil
+	}
+	dirs := []string{filepath.Dir(c.Source)}
+	if dir, _ := overlayPaths(c.Source); dirExists(dir) {
+		dirs = append(dirs, dir)
+	}
+	for _, file := range c.Layers {
+		dirs = append(dirs, filepath.Dir(file))
+	}
+
+	sort.Strings(dirs)
+	unique := dirs[:0]
+	for i, dir := range dirs {
+		if i == 0 || dir != dirs[i-1] {
+			unique = append(unique, dir)
+		}
+	}
+	return unique
+}
+
+// Affects reports whether a change to the named file can change the
+// config: it is a merged layer, or a file that would become one
+func (c *Config) Affects(name string) bool {
+	if c.Source == "" {
+		return false
+	}
+	name = filepath.Clean(name)
+	for _, file := range c.Layers {
+		if file == name {
+			return true
+		}
+	}
+
+	dir, hostFiles := overlayPaths(c.Source)
+	if filepath.Dir(name) == dir && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) {
+		return true
+	}
+	for _, hostFile := range hostFiles {
+		if hostFile == name {
+			return true
+		}
+	}
+	return false
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
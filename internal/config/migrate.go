@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version written by this build.
+// Files without a version key are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a config document from version from to from+1
+type migration struct {
+	from        int
+	description string
+	apply       func(root *yaml.Node) error
+}
+
+// migrations must stay ordered by from, one step per version
+var migrations = []migration{
+	{
+		from:        1,
+		description: "record the schema version; the layout is unchanged",
+		apply:       func(*yaml.Node) error { return nil },
+	},
+}
+
+// Migrate upgrades the config at path, and any layer that carries a version
+// key, to CurrentVersion on disk. Each original is kept as <file>.v<N>.bak.
+// Loading never writes; it upgrades in memory only. It returns notes
+// describing what was done.
+func Migrate(path string) ([]string, error) {
+	files, err := layerFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config: %w", err)
+		}
+		migrated, version, stepNotes, err := migrateLayer(file, path, data)
+		if err != nil {
+			return nil, err
+		}
+		if migrated == nil {
+			continue
+		}
+
+		backup := fmt.Sprintf("%s.v%d.bak", file, version)
+		if err := os.WriteFile(backup, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to back up config: %w", err)
+		}
+		mode := os.FileMode(0600)
+		if info, err := os.Stat(file); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := writeFileAtomic(file, migrated, mode); err != nil {
+			return nil, err
+		}
+		notes = append(notes, stepNotes...)
+		notes = append(notes, "previous config saved as "+backup)
+	}
+	return notes, nil
+}
+
+// migrateLayer upgrades one layer of the config at path in memory. The
+// config file itself is always migrated; other layers only when they carry
+// a version key, since fragments usually don't. It returns the upgraded
+// contents, or nil when there is nothing to do, the version the layer had
+// and notes describing each step.
+func migrateLayer(file, path string, data []byte) ([]byte, int, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, nil, fmt.Errorf("error parsing %s: %w", file, err)
+	}
+	root := documentRoot(&doc)
+	if root == nil || (filepath.Clean(file) != filepath.Clean(path) && mappingValue(root, "version") == nil) {
+		return nil, 0, nil, nil
+	}
+
+	version, notes, err := migrateDocument(root, migrations, CurrentVersion)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if version == CurrentVersion {
+		return nil, version, nil, nil
+	}
+
+	migrated, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return restoreBlankLines(data, migrated), version, notes, nil
+}
+
+// migrateDocument applies steps to a parsed config until it reaches target.
+// It returns the version the document had before.
+func migrateDocument(root *yaml.Node, steps []migration, target int) (int, []string, error) {
+	version, err := documentVersion(root)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version > target {
+		return 0, nil, fmt.Errorf("config version %d is newer than this rvmm supports (%d)", version, target)
+	}
+
+	var notes []string
+	for _, m := range steps {
+		if m.from < version || m.from >= target {
+			continue
+		}
+		if err := m.apply(root); err != nil {
+			return 0, nil, fmt.Errorf("failed to migrate config from version %d: %w", m.from, err)
+		}
+		setMappingScalar(root, "version", strconv.Itoa(m.from+1), "!!int")
+		notes = append(notes, fmt.Sprintf("migrated config from version %d to %d: %s", m.from, m.from+1, m.description))
+	}
+	return version, notes, nil
+}
+
+func documentVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, "version")
+	if node == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("version must be a positive integer, got %q", node.Value)
+	}
+	return version, nil
+}
+
+// documentRoot returns the top-level mapping of a parsed file, or nil for
+// an empty file
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode {
+		return root
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a mapping node
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingScalar sets key to a scalar value, adding it at the top if absent
+func setMappingScalar(mapping *yaml.Node, key, value, tag string) {
+	if node := mappingValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = tag
+		node.Value = value
+		node.Content = nil
+		return
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	// Keep the file's leading comment at the top
+	if len(mapping.Content) > 0 {
+		keyNode.HeadComment = mapping.Content[0].HeadComment
+		mapping.Content[0].HeadComment = ""
+	}
+	mapping.Content = append([]*yaml.Node{keyNode, valueNode}, mapping.Content...)
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// renameStep moves vm.resolution to vm.display, like a real schema change
+var renameStep = migration{
+	from:        2,
+	description: "rename vm.resolution to vm.display",
+	apply: func(root *yaml.Node) error {
+		vm := mappingValue(root, "vm")
+		if vm == nil {
+			return nil
+		}
+		for i := 0; i+1 < len(vm.Content); i += 2 {
+			if vm.Content[i].Value == "resolution" {
+				vm.Content[i].Value = "display"
+			}
+		}
+		return nil
+	},
+}
+
+func TestMigrateDocumentRewritesKeys(t *testing.T) {
+	src := `# header
+vm:
+  # guest display
+  resolution: "1920x1080"
+`
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	root := documentRoot(&doc)
+
+	steps := append(append([]migration{}, migrations...), renameStep)
+	version, notes, err := migrateDocument(root, steps, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 {
+		t.Errorf("version = %d, want 1", version)
+	}
+	if len(notes) != 2 {
+		t.Errorf("notes = %q, want one per step", notes)
+	}
+
+	out, err := encodeYAML(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, want := range []string{"version: 3", "# guest display\n  display: \"1920x1080\"", "# header"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("migrated file missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "resolution") {
+		t.Errorf("migrated file still has the old key:\n%s", got)
+	}
+}
+
+func TestMigrateDocumentSkipsAppliedSteps(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("version: 3\nvm:\n  resolution: x\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+	steps := append(append([]migration{}, migrations...), renameStep)
+	if _, notes, err := migrateDocument(documentRoot(&doc), steps, 3); err != nil || len(notes) != 0 {
+		t.Fatalf("migrateDocument() = %q, %v; want no steps", notes, err)
+	}
+}
+
+func TestMigrateDocumentRejectsNewerVersion(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("version: 9\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := migrateDocument(documentRoot(&doc), migrations, CurrentVersion); err == nil {
+		t.Fatal("migrateDocument() accepted a version newer than the target")
+	}
+}
+
+func TestLoadDoesNotRewriteOlderFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rvmm.yaml")
+	src := "# my config\noptions:\n  max_concurrent_runners: 2\n"
+	if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+	if data, _ := os.ReadFile(path); string(data) != src {
+		t.Errorf("LoadRaw rewrote the file:\n%s", data)
+	}
+	if _, err := os.Stat(path + ".v1.bak"); !os.IsNotExist(err) {
+		t.Errorf("LoadRaw wrote a backup: %v", err)
+	}
+
+	notes, err := Migrate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) == 0 {
+		t.Error("Migrate() reported nothing for a version 1 file")
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "version: 2") || !strings.Contains(string(data), "# my config") {
+		t.Errorf("Migrate wrote:\n%s", data)
+	}
+	if backup, _ := os.ReadFile(path + ".v1.bak"); string(backup) != src {
+		t.Errorf("backup = %q, want the original", backup)
+	}
+
+	if notes, err := Migrate(path); err != nil || len(notes) != 0 {
+		t.Errorf("second Migrate() = %q, %v; want nothing to do", notes, err)
+	}
+}
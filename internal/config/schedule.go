@@ -0,0 +1,326 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleWindow sets the runner concurrency for a recurring time window.
+// A window is either a weekday/time range (days, start, end) or a cron
+// expression marking its start plus a duration.
+type ScheduleWindow struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	// Weekdays the window starts on (mon..sun); empty means every day
+	Days []string `mapstructure:"days" yaml:"days"`
+	// Start and end as HH:MM; an end before the start crosses midnight
+	Start string `mapstructure:"start" yaml:"start"`
+	End   string `mapstructure:"end" yaml:"end"`
+	// Five-field cron expression (minute hour day month weekday)
+	Cron     string `mapstructure:"cron" yaml:"cron"`
+	Duration string `mapstructure:"duration" yaml:"duration"`
+	// IANA time zone, e.g. Europe/Berlin; empty means local time
+	Timezone             string `mapstructure:"timezone" yaml:"timezone"`
+	MaxConcurrentRunners int    `mapstructure:"max_concurrent_runners" yaml:"max_concurrent_runners"`
+}
+
+// maxCronWindow bounds how far back a cron window is searched
+const maxCronWindow = 7 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// DesiredRunners returns the concurrency for the given time: the first
+// active schedule window wins, otherwise options.max_concurrent_runners.
+// The name of the active window is empty when none applies.
+func (c *Config) DesiredRunners(now time.Time) (int, string) {
+	for i, w := range c.Options.Schedule {
+		if active, err := w.Active(now); err == nil && active {
+			return w.MaxConcurrentRunners, w.label(i)
+		}
+	}
+	return c.Options.MaxConcurrentRunners, ""
+}
+
+// PeakRunners returns the highest concurrency any window can request
+func (c *Config) PeakRunners() int {
+	peak := c.Options.MaxConcurrentRunners
+	for _, w := range c.Options.Schedule {
+		if w.MaxConcurrentRunners > peak {
+			peak = w.MaxConcurrentRunners
+		}
+	}
+	return peak
+}
+
+// Active reports whether the window covers the given time
+func (w ScheduleWindow) Active(now time.Time) (bool, error) {
+	loc, err := w.location()
+	if err != nil {
+		return false, err
+	}
+	now = now.In(loc)
+
+	if w.Cron != "" {
+		return w.cronActive(now)
+	}
+	return w.rangeActive(now)
+}
+
+// validate adds problems with the window to errs
+func (w ScheduleWindow) validate(prefix string, errs *problems) {
+	if w.MaxConcurrentRunners < 0 {
+		errs.add(prefix+".max_concurrent_runners", "must not be negative", "")
+	}
+	if _, err := w.location(); err != nil {
+		errs.add(prefix+".timezone", fmt.Sprintf("is invalid: %v", err), "use an IANA name such as Europe/Berlin")
+	}
+
+	if w.Cron != "" {
+		if len(w.Days) > 0 || w.Start != "" || w.End != "" {
+			errs.add(prefix, "must use either cron or days/start/end, not both", "")
+		}
+		if _, err := parseCron(w.Cron); err != nil {
+			errs.add(prefix+".cron", fmt.Sprintf("is invalid: %v", err), "e.g. \"0 2 * * sun\"")
+		}
+		if w.Duration == "" {
+			errs.add(prefix+".duration", "is required with cron", "e.g. 2h")
+		} else if d, err := time.ParseDuration(w.Duration); err != nil || d <= 0 || d > maxCronWindow {
+			errs.add(prefix+".duration", "must be a positive duration of at most 168h", "")
+		}
+		return
+	}
+
+	if w.Duration != "" {
+		errs.add(prefix+".duration", "is only used with cron", "")
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			errs.add(prefix+".days", fmt.Sprintf("unknown weekday %q", day), "use mon..sun")
+		}
+	}
+	start, startErr := parseClock(w.Start, 0)
+	if startErr != nil {
+		errs.add(prefix+".start", fmt.Sprintf("is invalid: %v", startErr), "")
+	}
+	end, endErr := parseClock(w.End, 24*60)
+	if endErr != nil {
+		errs.add(prefix+".end", fmt.Sprintf("is invalid: %v", endErr), "")
+	}
+	// An empty range would never be active
+	if startErr == nil && endErr == nil && start == end {
+		errs.add(prefix+".end", "must differ from start", "use 00:00 to 24:00 for a whole day")
+	}
+}
+
+func (w ScheduleWindow) label(index int) string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return fmt.Sprintf("schedule[%d]", index)
+}
+
+func (w ScheduleWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// rangeActive handles days/start/end windows; days refer to the day the
+// window starts, so a 22:00-06:00 window on fri also covers early saturday
+func (w ScheduleWindow) rangeActive(now time.Time) (bool, error) {
+	start, err := parseClock(w.Start, 0)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End, 24*60)
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+
+	if start <= end {
+		return w.onDay(today) && minute >= start && minute < end, nil
+	}
+	return (w.onDay(today) && minute >= start) || (w.onDay(yesterday) && minute < end), nil
+}
+
+// cronActive reports whether the cron expression fired within the last
+// duration
+func (w ScheduleWindow) cronActive(now time.Time) (bool, error) {
+	expr, err := parseCron(w.Cron)
+	if err != nil {
+		return false, err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return false, err
+	}
+	if duration > maxCronWindow {
+		duration = maxCronWindow
+	}
+
+	t := now.Truncate(time.Minute)
+	earliest := now.Add(-duration)
+	for ; t.After(earliest); t = t.Add(-time.Minute) {
+		if expr.matches(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (w ScheduleWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into minutes since midnight; empty returns def
+func parseClock(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	hh, mm, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// cronExpr is a parsed five-field cron expression
+type cronExpr struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+func (e *cronExpr) matches(t time.Time) bool {
+	if !e.minute[t.Minute()] || !e.hour[t.Hour()] || !e.month[int(t.Month())] {
+		return false
+	}
+	dom := e.dom[t.Day()]
+	dow := e.dow[int(t.Weekday())]
+	// Standard cron: when both day fields are restricted, either may match
+	switch {
+	case e.domAny && e.dowAny:
+		return true
+	case e.domAny:
+		return dow
+	case e.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+var cronMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+func parseCron(value string) (*cronExpr, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	dowNames := make(map[string]int, len(weekdays))
+	for name, day := range weekdays {
+		dowNames[name] = int(day)
+	}
+
+	var e cronExpr
+	var err error
+	if e.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if e.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if e.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day: %w", err)
+	}
+	if e.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if e.dow, err = parseCronField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("weekday: %w", err)
+	}
+	// 7 is an alias for Sunday
+	if e.dow[7] {
+		e.dow[0] = true
+	}
+	e.domAny = fields[2] == "*"
+	e.dowAny = fields[4] == "*"
+	return &e, nil
+}
+
+// parseCronField parses lists of values, ranges, * and /step
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(from, min, max, names); err != nil {
+				return nil, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(to, min, max, names); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func cronValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", value, min, max)
+	}
+	return n, nil
+}
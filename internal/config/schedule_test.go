@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func at(t *testing.T, value string) time.Time {
+	t.Helper()
+	ts, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func TestScheduleWindowActive(t *testing.T) {
+	workHours := ScheduleWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "19:00", Timezone: "UTC"}
+	fridayNight := ScheduleWindow{Days: []string{"Fri"}, Start: "22:00", End: "06:00", Timezone: "UTC"}
+	allDay := ScheduleWindow{Start: "00:00", End: "24:00", Timezone: "UTC"}
+	berlinMorning := ScheduleWindow{Start: "08:00", End: "09:00", Timezone: "Europe/Berlin"}
+	sundayMaintenance := ScheduleWindow{Cron: "0 2 * * sun", Duration: "2h", Timezone: "UTC"}
+	sundayAsSeven := ScheduleWindow{Cron: "0 2 * * 7", Duration: "1h", Timezone: "UTC"}
+	firstOrMonday := ScheduleWindow{Cron: "30 1 1 * mon", Duration: "30m", Timezone: "UTC"}
+	quarterHourly := ScheduleWindow{Cron: "*/15 * * * *", Duration: "1m", Timezone: "UTC"}
+	berlinNightly := ScheduleWindow{Cron: "0 2 * * *", Duration: "1h", Timezone: "Europe/Berlin"}
+
+	// 2026-10-16 is a Friday; Berlin is on UTC+2 until October 25
+	tests := []struct {
+		name   string
+		window ScheduleWindow
+		now    string
+		want   bool
+	}{
+		{"work hours", workHours, "2026-10-16 10:00", true},
+		{"work hours start", workHours, "2026-10-16 08:00", true},
+		{"before work hours", workHours, "2026-10-16 07:59", false},
+		{"work hours end is exclusive", workHours, "2026-10-16 19:00", false},
+		{"weekend", workHours, "2026-10-17 10:00", false},
+		{"friday night", fridayNight, "2026-10-16 23:00", true},
+		{"past midnight counts as friday", fridayNight, "2026-10-17 05:59", true},
+		{"friday night over", fridayNight, "2026-10-17 06:00", false},
+		{"thursday night", fridayNight, "2026-10-15 23:00", false},
+		{"friday early morning belongs to thursday", fridayNight, "2026-10-16 05:00", false},
+		{"all day", allDay, "2026-10-18 23:59", true},
+		{"time zone", berlinMorning, "2026-10-16 06:30", true},
+		{"time zone, UTC clock", berlinMorning, "2026-10-16 08:30", false},
+		{"cron start", sundayMaintenance, "2026-10-18 02:00", true},
+		{"cron within duration", sundayMaintenance, "2026-10-18 03:59", true},
+		{"cron after duration", sundayMaintenance, "2026-10-18 04:00", false},
+		{"cron other day", sundayMaintenance, "2026-10-17 02:30", false},
+		{"cron weekday 7 is sunday", sundayAsSeven, "2026-10-18 02:30", true},
+		{"cron weekday matches", firstOrMonday, "2026-10-12 01:45", true},
+		{"cron day of month matches", firstOrMonday, "2026-10-01 01:45", true},
+		{"cron neither day matches", firstOrMonday, "2026-10-13 01:45", false},
+		{"cron step", quarterHourly, "2026-10-16 10:15", true},
+		{"cron step missed", quarterHourly, "2026-10-16 10:16", false},
+		{"cron time zone", berlinNightly, "2026-10-16 00:30", true},
+		{"cron time zone, UTC clock", berlinNightly, "2026-10-16 02:30", false},
+	}
+	for _, tt := range tests {
+		got, err := tt.window.Active(at(t, tt.now))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Active(%s) = %v, want %v", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestDesiredRunners(t *testing.T) {
+	cfg := validConfig()
+	cfg.Options.MaxConcurrentRunners = 2
+	cfg.Options.Schedule = []ScheduleWindow{
+		{Name: "maintenance", Cron: "0 2 * * sun", Duration: "2h", Timezone: "UTC", MaxConcurrentRunners: 0},
+		{Start: "00:00", End: "24:00", Days: []string{"sun"}, Timezone: "UTC", MaxConcurrentRunners: 4},
+	}
+	tests := []struct {
+		now    string
+		want   int
+		window string
+	}{
+		{"2026-10-18 02:30", 0, "maintenance"},
+		{"2026-10-18 12:00", 4, "schedule[1]"},
+		{"2026-10-16 12:00", 2, ""},
+	}
+	for _, tt := range tests {
+		got, window := cfg.DesiredRunners(at(t, tt.now))
+		if got != tt.want || window != tt.window {
+			t.Errorf("DesiredRunners(%s) = %d, %q; want %d, %q", tt.now, got, window, tt.want, tt.window)
+		}
+	}
+	if peak := cfg.PeakRunners(); peak != 4 {
+		t.Errorf("PeakRunners() = %d, want 4", peak)
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	expr, err := parseCron("0 9-17/2 * jan-mar mon-fri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hour := 0; hour < 24; hour++ {
+		want := hour >= 9 && hour <= 17 && hour%2 == 1
+		if expr.hour[hour] != want {
+			t.Errorf("hour %d matched = %v, want %v", hour, expr.hour[hour], want)
+		}
+	}
+	if !expr.month[3] || expr.month[4] || !expr.dow[1] || expr.dow[0] {
+		t.Errorf("months %v, weekdays %v", expr.month, expr.dow)
+	}
+
+	for _, invalid := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * * funday",
+	} {
+		if _, err := parseCron(invalid); err == nil {
+			t.Errorf("parseCron(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		window ScheduleWindow
+		paths  []string
+	}{
+		{"range", ScheduleWindow{Days: []string{"mon"}, Start: "08:00", End: "19:00"}, nil},
+		{"whole day", ScheduleWindow{Start: "00:00", End: "24:00"}, nil},
+		{"cron", ScheduleWindow{Cron: "0 2 * * sun", Duration: "2h", Timezone: "Europe/Berlin"}, nil},
+		{"empty range", ScheduleWindow{Start: "08:00", End: "08:00"}, []string{"end"}},
+		{"bad clock", ScheduleWindow{Start: "8am", End: "24:30"}, []string{"start", "end"}},
+		{"bad weekday", ScheduleWindow{Days: []string{"someday"}, Start: "08:00", End: "09:00"}, []string{"days"}},
+		{"duration without cron", ScheduleWindow{Start: "08:00", End: "09:00", Duration: "1h"}, []string{"duration"}},
+		{"cron and range", ScheduleWindow{Cron: "0 2 * * *", Duration: "1h", Start: "08:00"}, []string{""}},
+		{"cron without duration", ScheduleWindow{Cron: "0 2 * * *"}, []string{"duration"}},
+		{"cron too long", ScheduleWindow{Cron: "0 2 * * *", Duration: "200h"}, []string{"duration"}},
+		{"bad cron", ScheduleWindow{Cron: "0 2 * *", Duration: "1h"}, []string{"cron"}},
+		{"bad time zone", ScheduleWindow{Start: "08:00", End: "09:00", Timezone: "Mars/Olympus"}, []string{"timezone"}},
+		{"negative", ScheduleWindow{Start: "08:00", End: "09:00", MaxConcurrentRunners: -1}, []string{"max_concurrent_runners"}},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Options.Schedule = []ScheduleWindow{tt.window}
+		var want []string
+		for _, path := range tt.paths {
+			want = append(want, strings.TrimSuffix("options.schedule[0]."+path, "."))
+		}
+		var got []string
+		if err := cfg.Validate(); err != nil {
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("%s: Validate() returned %T, want *ValidationError", tt.name, err)
+			}
+			for _, p := range verr.Problems {
+				got = append(got, p.Path)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: problems at %q, want %q", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the config
+// file, for editor validation and autocomplete
+func JSONSchema() map[string]any {
+	schema := schemaFor(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = "https://github.com/rxtech-lab/rvmm/rvmm.schema.json"
+	schema["title"] = "rvmm configuration"
+	return schema
+}
+
+// secretHelp is appended to the description of secret fields
+const secretHelp = "Secret; may be a reference such as env:NAME, file:/path or keychain:service/account"
+
+func schemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlName(f)
+			if name == "" {
+				continue
+			}
+			prop := schemaFor(f.Type)
+			if f.Tag.Get("single") == "true" {
+				// A lone value may be written instead of a one-element list
+				prop = map[string]any{"oneOf": []any{schemaFor(f.Type.Elem()), prop}}
+			}
+			description := f.Tag.Get("help")
+			if f.Tag.Get("secret") == "true" {
+				description = strings.TrimPrefix(description+". "+secretHelp, ". ")
+			}
+			if description != "" {
+				prop["description"] = description
+			}
+			if enum := f.Tag.Get("enum"); enum != "" {
+				prop["enum"] = strings.Split(enum, ",")
+			}
+			if f.Tag.Get("required") == "true" {
+				required = append(required, name)
+			}
+			properties[name] = prop
+		}
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]any{}
+}
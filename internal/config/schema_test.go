@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaProperty walks the properties of schema along a dotted path
+func schemaProperty(t *testing.T, schema map[string]any, path string) map[string]any {
+	t.Helper()
+	for _, key := range strings.Split(path, ".") {
+		properties, ok := schema["properties"].(map[string]any)
+		if !ok {
+			t.Fatalf("%s: no properties above %q", path, key)
+		}
+		if schema, ok = properties[key].(map[string]any); !ok {
+			t.Fatalf("%s: no property %q", path, key)
+		}
+	}
+	return schema
+}
+
+func TestJSONSchemaInclude(t *testing.T) {
+	include := schemaProperty(t, JSONSchema(), "include")
+	want := []any{
+		map[string]any{"type": "string"},
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}
+	if !reflect.DeepEqual(include["oneOf"], want) {
+		t.Errorf("include oneOf = %v, want %v", include["oneOf"], want)
+	}
+	if _, ok := include["type"]; ok {
+		t.Errorf("include has a type next to oneOf: %v", include)
+	}
+}
+
+func TestJSONSchemaRequired(t *testing.T) {
+	schema := JSONSchema()
+	tests := map[string][]string{
+		"github":   {"api_token", "registration_endpoint", "runner_url"},
+		"vm":       {"username", "password"},
+		"registry": {"image_name"},
+		"options":  {"working_directory"},
+	}
+	for path, want := range tests {
+		if got := schemaProperty(t, schema, path)["required"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s required = %v, want %v", path, got, want)
+		}
+	}
+	if _, ok := schemaProperty(t, schema, "daemon")["required"]; ok {
+		t.Error("daemon has a required list without required fields")
+	}
+}
+
+func TestJSONSchemaSecretDescription(t *testing.T) {
+	schema := JSONSchema()
+	token := schemaProperty(t, schema, "github.api_token")["description"]
+	if want := "Personal access token with repo or admin:org scope. " + secretHelp; token != want {
+		t.Errorf("api_token description = %q, want %q", token, want)
+	}
+	if password := schemaProperty(t, schema, "vm.password")["description"]; password != secretHelp {
+		t.Errorf("vm.password description = %q, want %q", password, secretHelp)
+	}
+}
+
+func TestJSONSchemaEncodes(t *testing.T) {
+	if _, err := json.Marshal(JSONSchema()); err != nil {
+		t.Fatal(err)
+	}
+}
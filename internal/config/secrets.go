@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+)
+
+// Secret reference prefixes. Fields tagged `secret:"true"` may hold a
+// reference instead of the secret itself; Load resolves it.
+const (
+	SecretEnv      = "env:"
+	SecretFile     = "file:"
+	SecretKeychain = "keychain:"
+)
+
+// IsSecretRef reports whether value is a secret reference
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretEnv) ||
+		strings.HasPrefix(value, SecretFile) ||
+		strings.HasPrefix(value, SecretKeychain)
+}
+
+// ResolveSecret returns the secret a reference points to. Values that are
+// not references are returned unchanged.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, SecretEnv):
+		name := strings.TrimPrefix(value, SecretEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+
+	case strings.HasPrefix(value, SecretFile):
+		path := os.ExpandEnv(strings.TrimPrefix(value, SecretFile))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case strings.HasPrefix(value, SecretKeychain):
+		service, account, ok := strings.Cut(strings.TrimPrefix(value, SecretKeychain), "/")
+		if !ok || service == "" || account == "" {
+			return "", fmt.Errorf("keychain reference must be keychain:service/account")
+		}
+		cmd := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("keychain item %s/%s not found: %w", service, account, err)
+		}
+		return strings.TrimRight(string(output), "\n"), nil
+	}
+	return value, nil
+}
+
+// SecretPaths returns the yaml paths of all fields tagged as secrets
+func SecretPaths() []string {
+	var paths []string
+	walkSecrets(reflect.TypeOf(Config{}), "", &paths)
+	return paths
+}
+
+// resolveSecrets replaces secret references with their values and remembers
+// the references so Unresolved can restore them
+func (c *Config) resolveSecrets() error {
+	for _, path := range SecretPaths() {
+		field, err := fieldByPath(reflect.ValueOf(c).Elem(), path)
+		if err != nil {
+			return err
+		}
+		ref := field.String()
+		if !IsSecretRef(ref) {
+			continue
+		}
+		secret, err := ResolveSecret(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if c.secretRefs == nil {
+			c.secretRefs = make(map[string]string)
+		}
+		c.secretRefs[path] = ref
+		field.SetString(secret)
+	}
+	return nil
+}
+
+// Unresolved returns a copy with secret references in place of the values
+// they resolved to, suitable for writing back to disk
+func (c *Config) Unresolved() *Config {
+	out := *c
+	out.secretRefs = nil
+	for path, ref := range c.secretRefs {
+		if field, err := fieldByPath(reflect.ValueOf(&out).Elem(), path); err == nil {
+			field.SetString(ref)
+		}
+	}
+	return &out
+}
+
+// RedactedValue replaces secrets in output; references are shown as-is
+const RedactedValue = "********"
+
+// Redacted returns a copy with every secret masked. References such as
+// env:NAME are kept since they don't reveal the secret.
+func (c *Config) Redacted() *Config {
+	out := c.Unresolved()
+	for _, path := range SecretPaths() {
+		field, err := fieldByPath(reflect.ValueOf(out).Elem(), path)
+		if err != nil || field.String() == "" || IsSecretRef(field.String()) {
+			continue
+		}
+		field.SetString(RedactedValue)
+	}
+	return out
+}
+
+func walkSecrets(t reflect.Type, prefix string, paths *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			walkSecrets(f.Type, path, paths)
+		case f.Tag.Get("secret") == "true":
+			*paths = append(*paths, path)
+		}
+	}
+}
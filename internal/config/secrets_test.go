@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSecurity puts a stand-in for the macOS security tool on PATH that
+// knows one keychain item, rvmm/github
+func fakeSecurity(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+[ "$3" = rvmm ] && [ "$5" = github ] || { echo "item not found" >&2; exit 44; }
+echo "ghp_keychain"
+`
+	if err := os.WriteFile(filepath.Join(bin, "security"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+}
+
+func TestIsSecretRef(t *testing.T) {
+	tests := map[string]bool{
+		"env:GH_TOKEN":          true,
+		"file:/etc/rvmm/token":  true,
+		"keychain:rvmm/github":  true,
+		"ghp_plain":             false,
+		"":                      false,
+		"ENV:GH_TOKEN":          false,
+		"https://env:x@host/":   false,
+		"environment:GH_TOKEN":  false,
+		"keychain-rvmm/github":  false,
+		" env:GH_TOKEN":         false,
+		"file:relative/token":   true,
+		"keychain:missing-part": true,
+	}
+	for value, want := range tests {
+		if got := IsSecretRef(value); got != want {
+			t.Errorf("IsSecretRef(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("ghp_file\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_TOKEN", "ghp_env")
+	t.Setenv("EMPTY_TOKEN", "")
+	t.Setenv("SECRETS_DIR", dir)
+	fakeSecurity(t)
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"ghp_plain", "ghp_plain", ""},
+		{"", "", ""},
+		{"env:GH_TOKEN", "ghp_env", ""},
+		{"env:EMPTY_TOKEN", "", ""},
+		{"env:MISSING_TOKEN", "", "MISSING_TOKEN is not set"},
+		{"file:" + filepath.Join(dir, "token"), "ghp_file", ""},
+		{"file:$SECRETS_DIR/token", "ghp_file", ""},
+		{"file:" + filepath.Join(dir, "missing"), "", "failed to read secret file"},
+		{"keychain:rvmm/github", "ghp_keychain", ""},
+		{"keychain:rvmm/other", "", "keychain item rvmm/other not found"},
+		{"keychain:rvmm", "", "keychain:service/account"},
+		{"keychain:/github", "", "keychain:service/account"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveSecret(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResolveSecret(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveSecret(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.GitHub.APIToken = "ghp_plain"
+	cfg.Registry.Password = "env:REGISTRY_PASSWORD"
+	cfg.PostHog.APIKey = ""
+
+	redacted := cfg.Redacted()
+	if redacted.GitHub.APIToken != RedactedValue {
+		t.Errorf("api_token = %q, want it masked", redacted.GitHub.APIToken)
+	}
+	if redacted.Registry.Password != "env:REGISTRY_PASSWORD" {
+		t.Errorf("registry password = %q, want the reference kept", redacted.Registry.Password)
+	}
+	if redacted.PostHog.APIKey != "" {
+		t.Errorf("empty posthog api_key = %q, want it left empty", redacted.PostHog.APIKey)
+	}
+	if cfg.GitHub.APIToken != "ghp_plain" {
+		t.Error("Redacted changed the original config")
+	}
+}
+
+func TestLoadResolvesSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rvmm.yaml")
+	data := `version: 2
+github:
+  api_token: env:GH_TOKEN
+registry:
+  password: file:` + filepath.Join(dir, "password") + `
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "password"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "github.api_token") {
+		t.Errorf("Load with GH_TOKEN unset: error = %v, want it to name github.api_token", err)
+	}
+
+	t.Setenv("GH_TOKEN", "ghp_env")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GitHub.APIToken != "ghp_env" || cfg.Registry.Password != "hunter2" {
+		t.Errorf("resolved secrets = %q, %q", cfg.GitHub.APIToken, cfg.Registry.Password)
+	}
+	if got := cfg.Redacted().GitHub.APIToken; got != "env:GH_TOKEN" {
+		t.Errorf("redacted api_token = %q, want the reference", got)
+	}
+
+	// Saving a loaded config writes the references back, never the secrets
+	cfg.GitHub.RunnerGroup = "mac-07"
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	saved := readFile(t, path)
+	for _, secret := range []string{"ghp_env", "hunter2"} {
+		if strings.Contains(saved, secret) {
+			t.Errorf("saved config contains the resolved secret %q:\n%s", secret, saved)
+		}
+	}
+	for _, want := range []string{"api_token: env:GH_TOKEN", "password: file:", "runner_group: mac-07"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved config is missing %q:\n%s", want, saved)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the size suffixes in increasing order of magnitude
+const sizeUnits = "KMGTPE"
+
+// ParseSize parses a disk size the way truncate(1) does: K, M, G, T, P and
+// E (or KiB, MiB, ...) are powers of 1024, KB, MB, GB, ... are powers of
+// 1000 and a bare number is bytes. Suffixes are case-insensitive, so "200g"
+// and "200G" are the same.
+func ParseSize(value string) (int64, error) {
+	s := strings.TrimSpace(strings.ToUpper(value))
+	if s == "" {
+		return 0, fmt.Errorf("size is empty")
+	}
+
+	// A trailing B selects decimal units and iB binary ones; either needs a unit
+	base, unitRequired := int64(1024), false
+	switch {
+	case strings.HasSuffix(s, "IB"):
+		s, unitRequired = strings.TrimSuffix(s, "IB"), true
+	case strings.HasSuffix(s, "B"):
+		s, base, unitRequired = strings.TrimSuffix(s, "B"), 1000, true
+	}
+
+	multiplier := int64(1)
+	if exp := strings.LastIndexByte(sizeUnits, lastByte(s)); exp >= 0 {
+		s = s[:len(s)-1]
+		for i := 0; i <= exp; i++ {
+			multiplier *= base
+		}
+	} else if unitRequired {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 200g)", value)
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 200g)", value)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return n * multiplier, nil
+}
+
+func lastByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[len(s)-1]
+}
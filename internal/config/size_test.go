@@ -0,0 +1,46 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"1K", 1 << 10},
+		{"200g", 200 << 30},
+		{"200G", 200 << 30},
+		{"200GiB", 200 << 30},
+		{"200gib", 200 << 30},
+		{"200GB", 200e9},
+		{"100MB", 100e6},
+		{"1KB", 1000},
+		{"2T", 2 << 40},
+		{"1E", 1 << 60},
+		{" 50G ", 50 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSizeInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", "0", "-5G", "G", "GB", "12B", "12iB", "1.5G", "200X", "abc",
+		"8E", "9223372036854775807K",
+	} {
+		if got, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) = %d, want an error", in, got)
+		}
+	}
+	if got, err := ParseSize("9223372036854775807"); err != nil || got != math.MaxInt64 {
+		t.Errorf("ParseSize(max) = %d, %v", got, err)
+	}
+}
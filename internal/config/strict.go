@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownKeys lists keys in a config document that don't map to a Config
+// field, with a suggestion when a known key is spelled similarly
+func unknownKeys(root *yaml.Node) []string {
+	var problems []string
+	checkKeys(root, reflect.TypeOf(Config{}), "", &problems)
+	return problems
+}
+
+func checkKeys(node *yaml.Node, t reflect.Type, prefix string, problems *[]string) {
+	switch t.Kind() {
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), problems)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := make(map[string]reflect.Type)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			fields[name] = t.Field(i).Type
+			names = append(names, name)
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fieldType, ok := fields[key]
+		if !ok {
+			msg := fmt.Sprintf("%s is not a known key", path)
+			if suggestion := closestKey(key, names); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
+			*problems = append(*problems, msg)
+			continue
+		}
+		checkKeys(node.Content[i+1], fieldType, path, problems)
+	}
+}
+
+// closestKey returns the candidate within a small edit distance of key
+func closestKey(key string, candidates []string) string {
+	best, bestDist := "", len(key)/3+2
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(key), c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
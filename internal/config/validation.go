@@ -2,60 +2,476 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"syscall"
+	"text/template"
+	"time"
 )
 
-// Validate checks that all required configuration fields are present
+// FieldError is a validation problem with the field it concerns and, when
+// useful, a hint on how to fix it
+type FieldError struct {
+	Path    string
+	Message string
+	Hint    string
+}
+
+func (e FieldError) String() string {
+	s := e.Path + " " + e.Message
+	if e.Hint != "" {
+		s += " (" + e.Hint + ")"
+	}
+	return s
+}
+
+// ValidationError lists every problem found by Validate
+type ValidationError struct {
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// problems collects FieldErrors while validating
+type problems []FieldError
+
+func (p *problems) add(path, message, hint string) {
+	*p = append(*p, FieldError{Path: path, Message: message, Hint: hint})
+}
+
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+var (
+	displayRegex      = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*(px|pt)?$`)
+	reverseDNSRegex   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*(\.[A-Za-z0-9][A-Za-z0-9-]*)+$`)
+	registryHostRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]+)?$`)
+	// OCI reference grammar from the distribution spec
+	ociNameComponent  = `[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*`
+	ociReferenceRegex = regexp.MustCompile(
+		`^(?:[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(?::[0-9]+)?/)?` +
+			ociNameComponent + `(?:/` + ociNameComponent + `)*` +
+			`(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?` +
+			`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+)
+
+// Validate checks that the configuration is complete and consistent. The
+// returned error is a *ValidationError listing every problem.
 func (c *Config) Validate() error {
-	var errs []string
+	var errs problems
 
 	// GitHub validation
 	if c.GitHub.APIToken == "" {
-		errs = append(errs, "github.api_token is required")
+		errs.add("github.api_token", "is required", "")
 	}
+	var endpointScope, runnerScope string
 	if c.GitHub.RegistrationEndpoint == "" {
-		errs = append(errs, "github.registration_endpoint is required")
-	} else if _, err := url.Parse(c.GitHub.RegistrationEndpoint); err != nil {
-		errs = append(errs, "github.registration_endpoint must be a valid URL")
+		errs.add("github.registration_endpoint", "is required", "")
+	} else if scope, err := registrationScope(c.GitHub.RegistrationEndpoint); err != nil {
+		errs.add("github.registration_endpoint", err.Error(),
+			"e.g. https://api.github.com/orgs/ORG/actions/runners/registration-token")
+	} else {
+		endpointScope = scope
 	}
 	if c.GitHub.RunnerURL == "" {
-		errs = append(errs, "github.runner_url is required")
+		errs.add("github.runner_url", "is required", "")
+	} else if scope, err := runnerURLScope(c.GitHub.RunnerURL); err != nil {
+		errs.add("github.runner_url", err.Error(), "e.g. https://github.com/ORG or https://github.com/OWNER/REPO")
+	} else {
+		runnerScope = scope
+	}
+	if endpointScope != "" && runnerScope != "" && !strings.EqualFold(endpointScope, runnerScope) {
+		errs.add("github.runner_url",
+			fmt.Sprintf("refers to %s but github.registration_endpoint refers to %s", runnerScope, endpointScope),
+			"both must point at the same organization, repository or enterprise")
+	}
+	if c.GitHub.RunnerNameTemplate != "" {
+		if _, err := template.New("runner_name").Parse(c.GitHub.RunnerNameTemplate); err != nil {
+			errs.add("github.runner_name_template", fmt.Sprintf("is not a valid template: %v", err), "")
+		}
 	}
 
 	// Registry validation
+	if c.Registry.URL != "" && !registryHostRegex.MatchString(c.Registry.URL) {
+		errs.add("registry.url", "must be a registry host", "e.g. ghcr.io or registry.local:5000, without https://")
+	}
 	if c.Registry.ImageName == "" {
-		errs = append(errs, "registry.image_name is required")
+		errs.add("registry.image_name", "is required", "")
+	} else if ref := c.Registry.Reference(); !ociReferenceRegex.MatchString(ref) {
+		errs.add("registry.image_name", fmt.Sprintf("%q is not a valid OCI image reference", ref),
+			"use lowercase [host/]name[:tag][@digest], e.g. ghcr.io/org/runner:latest")
+	}
+
+	if c.Registry.PullRetries < 0 {
+		errs.add("registry.pull_retries", "must not be negative", "")
+	}
+	if !validDuration(c.Registry.PullRetryDelay) {
+		errs.add("registry.pull_retry_delay", "must be a positive duration", "e.g. 30s")
 	}
 
 	// VM validation
 	if c.VM.Username == "" {
-		errs = append(errs, "vm.username is required")
+		errs.add("vm.username", "is required", "")
 	}
 	if c.VM.Password == "" {
-		errs = append(errs, "vm.password is required")
+		errs.add("vm.password", "is required", "")
+	}
+	if c.VM.Display != "" && !displayRegex.MatchString(c.VM.Display) {
+		errs.add("vm.display", "must be WIDTHxHEIGHT", "e.g. 1920x1080")
+	}
+
+	if c.VM.CPU < 0 {
+		errs.add("vm.cpu", "must not be negative", "")
+	}
+	if c.VM.MemoryMB < 0 {
+		errs.add("vm.memory_mb", "must not be negative", "")
+	}
+	if c.VM.DiskSizeGB < 0 {
+		errs.add("vm.disk_size_gb", "must not be negative", "")
+	}
+
+	mountNames := make(map[string]bool)
+	for i, mount := range c.VM.Mounts {
+		field := fmt.Sprintf("vm.mounts[%d]", i)
+		if mount.Name == "" {
+			errs.add(field+".name", "is required", "")
+		} else if strings.ContainsAny(mount.Name, ":,/") {
+			errs.add(field+".name", "must not contain ':', ',' or '/'", "")
+		} else if mountNames[mount.Name] {
+			errs.add(field+".name", fmt.Sprintf("%q is used more than once", mount.Name), "")
+		}
+		mountNames[mount.Name] = true
+		if mount.HostPath == "" {
+			errs.add(field+".host_path", "is required", "")
+		} else if strings.Contains(mount.HostPath, ":") {
+			errs.add(field+".host_path", "must not contain ':'", "")
+		}
+	}
+
+	switch c.VM.Network.Mode {
+	case "", NetworkShared:
+		if len(c.VM.Network.Allow) > 0 || c.VM.Network.Interface != "" {
+			errs.add("vm.network", "allow and interface require mode softnet or bridged", "")
+		}
+	case NetworkSoftnet:
+		for i, cidr := range c.VM.Network.Allow {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				errs.add(fmt.Sprintf("vm.network.allow[%d]", i), "must be a CIDR", "e.g. 10.0.0.0/8")
+			}
+		}
+		if c.VM.Network.Interface != "" {
+			errs.add("vm.network.interface", "is only used with mode bridged", "")
+		}
+	case NetworkBridged:
+		if c.VM.Network.Interface == "" {
+			errs.add("vm.network.interface", "is required when mode is bridged", "e.g. en0")
+		}
+		if len(c.VM.Network.Allow) > 0 {
+			errs.add("vm.network.allow", "is only used with mode softnet", "")
+		}
+	default:
+		errs.add("vm.network.mode", fmt.Sprintf("must be one of %s, %s, %s",
+			NetworkShared, NetworkSoftnet, NetworkBridged), "")
+	}
+
+	if c.VM.Snapshot.Enabled {
+		if len(c.VM.Mounts) > 0 {
+			errs.add("vm.snapshot", "cannot be combined with vm.mounts", "a resumed VM cannot attach new directories")
+		}
+		if !validDuration(c.VM.Snapshot.ResumeTimeout) {
+			errs.add("vm.snapshot.resume_timeout", "must be a positive duration", "e.g. 1m")
+		}
+	}
+
+	bootDurations := []struct {
+		name  string
+		value string
+	}{
+		{"ip_timeout", c.VM.Boot.IPTimeout},
+		{"ip_poll_interval", c.VM.Boot.IPPollInterval},
+		{"ssh_timeout", c.VM.Boot.SSHTimeout},
+		{"ssh_poll_interval", c.VM.Boot.SSHPollInterval},
+		{"probe_timeout", c.VM.Boot.ProbeTimeout},
+		{"probe_poll_interval", c.VM.Boot.ProbePollInterval},
+	}
+	for _, bd := range bootDurations {
+		if !validDuration(bd.value) {
+			errs.add("vm.boot."+bd.name, "must be a positive duration", "e.g. 5m")
+		}
+	}
+
+	for i, probe := range c.VM.Readiness {
+		field := fmt.Sprintf("vm.readiness_probes[%d]", i)
+		switch probe.Type {
+		case ProbeRunnerBinary:
+		case ProbeDisk, ProbeNetwork:
+			if probe.Target == "" {
+				errs.add(field+".target", "is required for type "+probe.Type, "")
+			}
+		case ProbeCommand:
+			if probe.Command == "" {
+				errs.add(field+".command", "is required for type command", "")
+			}
+		default:
+			errs.add(field+".type", fmt.Sprintf("must be one of %s, %s, %s, %s",
+				ProbeRunnerBinary, ProbeDisk, ProbeNetwork, ProbeCommand), "")
+		}
+	}
+
+	for i, step := range c.VM.Bootstrap {
+		field := fmt.Sprintf("vm.bootstrap[%d]", i)
+		if (step.Script == "") == (step.File == "") {
+			errs.add(field, "must set exactly one of script or file", "")
+		}
+		for _, kv := range step.Env {
+			if name, _, ok := strings.Cut(kv, "="); !ok || name == "" {
+				errs.add(field+".env", fmt.Sprintf("entry %q must be in NAME=value form", kv), "")
+			}
+		}
 	}
 
 	// Options validation
+	if c.Options.TruncateSize != "" {
+		if _, err := ParseSize(c.Options.TruncateSize); err != nil {
+			errs.add("options.truncate_size", fmt.Sprintf("%q is not a size", c.Options.TruncateSize), "e.g. 200g or 100GB")
+		}
+	}
+	if c.Options.WorkingDirectory == "" {
+		errs.add("options.working_directory", "is required", "")
+	} else if !filepath.IsAbs(c.Options.WorkingDirectory) {
+		errs.add("options.working_directory", "must be an absolute path", "e.g. /Users/admin/vm")
+	}
 	if c.Options.MaxConcurrentRunners < 1 {
-		errs = append(errs, "options.max_concurrent_runners must be at least 1")
+		errs.add("options.max_concurrent_runners", "must be at least 1", "")
+	}
+	for i, window := range c.Options.Schedule {
+		window.validate(fmt.Sprintf("options.schedule[%d]", i), &errs)
+	}
+
+	if c.Options.WarmPool.Size < 0 {
+		errs.add("options.warm_pool.size", "must not be negative", "")
+	}
+	if c.Options.WarmPool.Size > 0 {
+		if !validDuration(c.Options.WarmPool.MaxAge) {
+			errs.add("options.warm_pool.max_age", "must be a positive duration", "e.g. 2h")
+		}
+		for i, mount := range c.VM.Mounts {
+			if mount.PerSlot {
+				errs.add(fmt.Sprintf("vm.mounts[%d].per_slot", i), "cannot be used with options.warm_pool",
+					"warm VMs boot before a slot is assigned")
+			}
+		}
+	}
+
+	if c.Options.JobTimeout != "" && !validDuration(c.Options.JobTimeout) {
+		errs.add("options.job_timeout", "must be a positive duration", "e.g. 6h")
+	}
+
+	if c.Options.Diagnostics.Enabled && !validDuration(c.Options.Diagnostics.SystemLogWindow) {
+		errs.add("options.diagnostics.system_log_window", "must be a positive duration", "e.g. 15m")
+	}
+
+	// Hook validation
+	hookStages := []struct {
+		name  string
+		hooks []HookConfig
+	}{
+		{"after_clone", c.Options.Hooks.AfterClone},
+		{"after_ip", c.Options.Hooks.AfterIP},
+		{"before_runner", c.Options.Hooks.BeforeRunner},
+		{"after_job", c.Options.Hooks.AfterJob},
+		{"on_failure", c.Options.Hooks.OnFailure},
+	}
+	for _, stage := range hookStages {
+		for i, hook := range stage.hooks {
+			field := fmt.Sprintf("options.hooks.%s[%d]", stage.name, i)
+			if hook.Command == "" {
+				errs.add(field+".command", "is required", "")
+			}
+			if !validDuration(hook.Timeout) {
+				errs.add(field+".timeout", "must be a positive duration", "e.g. 30s")
+			}
+		}
+	}
+
+	// Daemon validation
+	if c.Daemon.Label != "" && !reverseDNSRegex.MatchString(c.Daemon.Label) {
+		errs.add("daemon.label", "must be a reverse-DNS name", "e.g. com.example.rvmm")
+	}
+	switch c.Daemon.Manager {
+	case "", "launchd", "systemd":
+	default:
+		errs.add("daemon.manager", fmt.Sprintf("unknown service manager %q", c.Daemon.Manager), "use launchd or systemd, or leave empty")
 	}
 
 	// PostHog validation
 	if c.PostHog.Enabled {
 		if c.PostHog.APIKey == "" {
-			errs = append(errs, "posthog.api_key is required when posthog is enabled")
+			errs.add("posthog.api_key", "is required when posthog is enabled", "")
 		}
 		if c.PostHog.MachineLabel == "" {
-			errs = append(errs, "posthog.machine_label is required when posthog is enabled")
+			errs.add("posthog.machine_label", "is required when posthog is enabled", "")
 		}
 		if c.PostHog.Host == "" {
-			errs = append(errs, "posthog.host is required when posthog is enabled")
+			errs.add("posthog.host", "is required when posthog is enabled", "")
+		}
+	}
+
+	return errs.err()
+}
+
+// ValidateHost checks the settings that depend on the machine rvmm runs on
+// rather than on the file, so a config can be validated elsewhere
+func (c *Config) ValidateHost() error {
+	var errs problems
+	if filepath.IsAbs(c.Options.WorkingDirectory) {
+		if err := checkWritableDir(c.Options.WorkingDirectory); err != nil {
+			errs.add("options.working_directory", err.Error(), "create it or choose a directory owned by the runner user")
+		}
+	}
+	return errs.err()
+}
+
+// ValidateCapacity checks that max_concurrent_runners VMs with the configured
+// CPU and memory overrides fit on a host with the given resources
+func (c *Config) ValidateCapacity(hostCPUs int, hostMemoryMB int) error {
+	var errs problems
+	// Warm VMs run alongside the active ones; schedule windows may raise
+	// concurrency above max_concurrent_runners
+	runners := c.PeakRunners() + c.Options.WarmPool.Size
+
+	if c.VM.CPU > 0 && hostCPUs > 0 && c.VM.CPU*runners > hostCPUs {
+		errs.add("vm.cpu", fmt.Sprintf(
+			"(%d) x VMs (%d, peak concurrency + warm_pool.size) exceeds host CPU count (%d)",
+			c.VM.CPU, runners, hostCPUs), "lower vm.cpu or the number of concurrent VMs")
+	}
+	if c.VM.MemoryMB > 0 && hostMemoryMB > 0 && c.VM.MemoryMB*runners > hostMemoryMB {
+		errs.add("vm.memory_mb", fmt.Sprintf(
+			"(%d) x VMs (%d, peak concurrency + warm_pool.size) exceeds host memory (%d MB)",
+			c.VM.MemoryMB, runners, hostMemoryMB), "lower vm.memory_mb or the number of concurrent VMs")
+	}
+
+	return errs.err()
+}
+
+// registrationScope returns "orgs/ORG", "repos/OWNER/REPO" or
+// "enterprises/ENT" for a registration token endpoint, on github.com
+// (api.github.com) or GitHub Enterprise Server (HOST/api/v3)
+func registrationScope(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("must be an http(s) URL")
+	}
+	path := strings.TrimPrefix(strings.Trim(u.Path, "/"), "api/v3/")
+	parts := strings.Split(path, "/")
+	n := len(parts)
+	if n < 4 || strings.Join(parts[n-3:], "/") != "actions/runners/registration-token" {
+		return "", fmt.Errorf("must end in /actions/runners/registration-token")
+	}
+	scope := parts[:n-3]
+	switch {
+	case len(scope) == 2 && (scope[0] == "orgs" || scope[0] == "enterprises"):
+	case len(scope) == 3 && scope[0] == "repos":
+	default:
+		return "", fmt.Errorf("must be an orgs/ORG, repos/OWNER/REPO or enterprises/ENT endpoint")
+	}
+	return strings.Join(scope, "/"), nil
+}
+
+// runnerURLScope maps a runner URL to the same form as registrationScope
+func runnerURLScope(runnerURL string) (string, error) {
+	u, err := url.Parse(runnerURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("must be an http(s) URL")
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "enterprises":
+		return "enterprises/" + parts[1], nil
+	case len(parts) == 1 && parts[0] != "":
+		return "orgs/" + parts[0], nil
+	case len(parts) == 2:
+		return "repos/" + parts[0] + "/" + parts[1], nil
+	}
+	return "", fmt.Errorf("must point at an organization, repository or enterprise")
+}
+
+// checkWritableDir checks that dir, or the closest existing parent it would
+// be created in, is a writable directory
+func checkWritableDir(dir string) error {
+	for path := dir; ; path = filepath.Dir(path) {
+		info, err := os.Stat(path)
+		// A file in the path shows up as ENOTDIR; walk up to report it
+		if (os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)) && path != filepath.Dir(path) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot be checked: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", path)
 		}
+		// 2 is W_OK
+		if err := syscall.Access(path, 2); err != nil {
+			if path == dir {
+				return fmt.Errorf("is not writable")
+			}
+			return fmt.Errorf("does not exist and %s is not writable", path)
+		}
+		return nil
+	}
+}
+
+// validDuration reports whether value is empty or a positive Go duration
+func validDuration(value string) bool {
+	if value == "" {
+		return true
 	}
+	d, err := time.ParseDuration(value)
+	return err == nil && d > 0
+}
 
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, "; "))
+// runnerNameWarning returns a warning when github.runner_name_template can
+// render the same name on two hosts, or "" when names are unique. Runner
+// registrations are not replaced, so a clash makes config.sh fail.
+func (c *Config) runnerNameWarning() string {
+	text := c.GitHub.RunnerNameTemplate
+	if text == "" {
+		return ""
+	}
+	tmpl, err := template.New("runner_name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return ""
+	}
+
+	type nameData struct {
+		Pool   string
+		HostID string
+		Slot   int
+		Suffix string
+	}
+	var a, b strings.Builder
+	if tmpl.Execute(&a, nameData{Pool: "pool", HostID: "host-a", Suffix: "aaaaaa"}) != nil ||
+		tmpl.Execute(&b, nameData{Pool: "pool", HostID: "host-b", Suffix: "bbbbbb"}) != nil {
+		return ""
+	}
+	if a.String() == b.String() {
+		return "github.runner_name_template uses neither {{.HostID}} nor {{.Suffix}}, so hosts sharing this config register the same runner names"
 	}
-	return nil
+	return ""
 }
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistrationScope(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  string
+	}{
+		{"https://api.github.com/orgs/acme/actions/runners/registration-token", "orgs/acme", ""},
+		{"https://api.github.com/repos/acme/app/actions/runners/registration-token", "repos/acme/app", ""},
+		{"https://api.github.com/enterprises/big/actions/runners/registration-token", "enterprises/big", ""},
+		{"https://ghe.example.com/api/v3/orgs/acme/actions/runners/registration-token/", "orgs/acme", ""},
+		{"api.github.com/orgs/acme/actions/runners/registration-token", "", "http(s) URL"},
+		{"ftp://api.github.com/orgs/acme/actions/runners/registration-token", "", "http(s) URL"},
+		{"https://api.github.com/orgs/acme/actions/runners", "", "registration-token"},
+		{"https://api.github.com/orgs/acme/actions/runners/remove-token", "", "registration-token"},
+		{"https://api.github.com/users/me/actions/runners/registration-token", "", "orgs/ORG"},
+		{"https://api.github.com/repos/acme/actions/runners/registration-token", "", "orgs/ORG"},
+	}
+	for _, tt := range tests {
+		got, err := registrationScope(tt.endpoint)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("registrationScope(%q) error = %v, want %q", tt.endpoint, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("registrationScope(%q) = %q, %v; want %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
+
+func TestRunnerURLScope(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https://github.com/acme", "orgs/acme", false},
+		{"https://github.com/acme/", "orgs/acme", false},
+		{"https://github.com/acme/app", "repos/acme/app", false},
+		{"https://github.com/enterprises/big", "enterprises/big", false},
+		{"https://ghe.example.com/acme/app", "repos/acme/app", false},
+		{"https://github.com", "", true},
+		{"https://github.com/acme/app/tree", "", true},
+		{"github.com/acme", "", true},
+		{"ssh://github.com/acme", "", true},
+	}
+	for _, tt := range tests {
+		got, err := runnerURLScope(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("runnerURLScope(%q) = %q, want an error", tt.url, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("runnerURLScope(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
+
+func TestRegexRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   func(string) bool
+		valid   []string
+		invalid []string
+	}{
+		{
+			name:    "display",
+			match:   displayRegex.MatchString,
+			valid:   []string{"1920x1080", "3840x2160", "1024x768px", "1440x900pt"},
+			invalid: []string{"", "1920", "1920X1080", "0x1080", "1920x0", "1920x1080em", "-1x5", "1920 x 1080"},
+		},
+		{
+			name:  "OCI reference",
+			match: ociReferenceRegex.MatchString,
+			valid: []string{
+				"runner", "runner:latest", "ghcr.io/acme/runner:v1.2",
+				"registry.local:5000/macos/sonoma:xcode-15",
+				"ghcr.io/acme/runner@sha256:" + strings.Repeat("a", 64),
+				"a__b/c-d.e", "library/runner:1.0_rc",
+			},
+			invalid: []string{
+				"", "Runner", "ghcr.io/Acme/runner", "runner:", "runner:-tag",
+				"https://ghcr.io/acme/runner", "runner@sha256:abc", "runner::tag", "-runner",
+				"runner:" + strings.Repeat("t", 129),
+			},
+		},
+		{
+			name:    "reverse DNS",
+			match:   reverseDNSRegex.MatchString,
+			valid:   []string{"com.mirego.ekiden", "com.example.rvmm", "io.acme.runner-2", "a.b"},
+			invalid: []string{"", "rvmm", "com..example", ".com.example", "com.example.", "1com.example", "com.exa mple", "com/example"},
+		},
+		{
+			name:    "registry host",
+			match:   registryHostRegex.MatchString,
+			valid:   []string{"ghcr.io", "registry.local:5000", "localhost", "10.0.0.1:443"},
+			invalid: []string{"https://ghcr.io", "ghcr.io/acme", "-ghcr.io", "ghcr.io:", "ghcr.io:port"},
+		},
+	}
+	for _, tt := range tests {
+		for _, v := range tt.valid {
+			if !tt.match(v) {
+				t.Errorf("%s: %q should be valid", tt.name, v)
+			}
+		}
+		for _, v := range tt.invalid {
+			if tt.match(v) {
+				t.Errorf("%s: %q should be invalid", tt.name, v)
+			}
+		}
+	}
+}
+
+func TestCheckWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr string
+	}{
+		{"existing", dir, ""},
+		{"missing under writable parent", filepath.Join(dir, "a", "b"), ""},
+		{"file", file, "is not a directory"},
+		{"under a file", filepath.Join(file, "sub"), "is not a directory"},
+	}
+	if os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "ro")
+		if err := os.Mkdir(readOnly, 0500); err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests,
+			struct{ name, dir, wantErr string }{"read-only", readOnly, "is not writable"},
+			struct{ name, dir, wantErr string }{"missing under read-only", filepath.Join(readOnly, "x"), "ro is not writable"},
+		)
+	}
+
+	for _, tt := range tests {
+		err := checkWritableDir(tt.dir)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: checkWritableDir() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: checkWritableDir() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// validConfig returns a config that passes Validate
+func validConfig() *Config {
+	cfg := Default()
+	cfg.GitHub.APIToken = "ghp_test"
+	cfg.GitHub.RegistrationEndpoint = "https://api.github.com/orgs/acme/actions/runners/registration-token"
+	cfg.GitHub.RunnerURL = "https://github.com/acme"
+	cfg.Registry.ImageName = "runner:latest"
+	cfg.Options.WorkingDirectory = "/nonexistent/rvmm"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		paths  []string
+	}{
+		{"valid", func(*Config) {}, nil},
+		{"scope mismatch", func(c *Config) { c.GitHub.RunnerURL = "https://github.com/other" }, []string{"github.runner_url"}},
+		{"bad endpoint", func(c *Config) { c.GitHub.RegistrationEndpoint = "https://api.github.com/orgs/acme" }, []string{"github.registration_endpoint"}},
+		{"display", func(c *Config) { c.VM.Display = "big" }, []string{"vm.display"}},
+		{"truncate size", func(c *Config) { c.Options.TruncateSize = "lots" }, []string{"options.truncate_size"}},
+		{"relative working directory", func(c *Config) { c.Options.WorkingDirectory = "vm" }, []string{"options.working_directory"}},
+		{"label", func(c *Config) { c.Daemon.Label = "rvmm" }, []string{"daemon.label"}},
+		{"image", func(c *Config) { c.Registry.ImageName = "Runner:latest" }, []string{"registry.image_name"}},
+		{"registry url", func(c *Config) { c.Registry.URL = "https://ghcr.io" }, []string{"registry.url", "registry.image_name"}},
+		{"manager", func(c *Config) { c.Daemon.Manager = "upstart" }, []string{"daemon.manager"}},
+		{"job timeout", func(c *Config) { c.Options.JobTimeout = "forever" }, []string{"options.job_timeout"}},
+		{"several", func(c *Config) {
+			c.VM.Display = "big"
+			c.Daemon.Label = "rvmm"
+		}, []string{"vm.display", "daemon.label"}},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		tt.modify(cfg)
+		var got []string
+		if err := cfg.Validate(); err != nil {
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("%s: Validate() returned %T, want *ValidationError", tt.name, err)
+			}
+			for _, p := range verr.Problems {
+				got = append(got, p.Path)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.paths, ",") {
+			t.Errorf("%s: problems at %q, want %q", tt.name, got, tt.paths)
+		}
+	}
+}
+
+func TestRunnerNameWarning(t *testing.T) {
+	tests := []struct {
+		template string
+		warn     bool
+	}{
+		{DefaultRunnerNameTemplate, false},
+		{"{{.Pool}}-{{.HostID}}-{{.Slot}}", false},
+		{"{{.Pool}}-{{.Suffix}}", false},
+		{"{{.Pool}}-{{.Slot}}", true},
+		{"static", true},
+		{"{{.Pool", false},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.GitHub.RunnerNameTemplate = tt.template
+		if got := cfg.runnerNameWarning(); (got != "") != tt.warn {
+			t.Errorf("runnerNameWarning() for %q = %q, want warning %v", tt.template, got, tt.warn)
+		}
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	cfg := validConfig()
+	cfg.Options.WorkingDirectory = t.TempDir()
+	if err := cfg.ValidateHost(); err != nil {
+		t.Errorf("ValidateHost() = %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Options.WorkingDirectory = file
+	var verr *ValidationError
+	if err := cfg.ValidateHost(); !errors.As(err, &verr) || verr.Problems[0].Path != "options.working_directory" {
+		t.Errorf("ValidateHost() = %v, want a working_directory problem", err)
+	}
+	// The file alone is still valid
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want no host checks", err)
+	}
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Save writes cfg to the config at path. Only fields that differ from what
+// is currently loaded from path are written. A field set by an overlay goes
+// to that overlay and anything else to path itself, so include files shared
+// with other hosts are left alone and the new value overrides them. Files
+// are patched in place, keeping comments, key order and unknown keys, and
+// the previous contents are kept as <file>.bak. A missing file is written in
+// full.
+func Save(path string, cfg *Config) error {
+	return save(path, cfg, false)
+}
+
+// SaveIncludes is like Save but writes a field set by an include back to
+// that include, which changes every config sharing it
+func SaveIncludes(path string, cfg *Config) error {
+	return save(path, cfg, true)
+}
+
+func save(path string, cfg *Config, includes bool) error {
+	cfg = cfg.Unresolved()
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		data, err := encodeYAML(cfg)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data, 0600)
+	}
+
+	current, err := LoadRaw(path)
+	if err != nil {
+		return err
+	}
+	overlays, err := overlayFiles(path)
+	if err != nil {
+		return err
+	}
+	writable := map[string]bool{filepath.Clean(path): true}
+	for _, overlay := range overlays {
+		writable[filepath.Clean(overlay)] = true
+	}
+
+	// Group changed fields by the file they are written to
+	var files []string
+	changes := make(map[string][]string)
+	for _, field := range Diff(current, cfg) {
+		file := path
+		if source := current.ValueSource(field); strings.HasPrefix(source, SourceFile+" ") {
+			file = strings.TrimPrefix(source, SourceFile+" ")
+			if !includes {
+				file = writableLayer(current.Layers, file, writable, path)
+			}
+		}
+		if _, ok := changes[file]; !ok {
+			files = append(files, file)
+		}
+		changes[file] = append(changes[file], field)
+	}
+
+	for _, file := range files {
+		if err := patchFile(file, path, cfg, changes[file]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writableLayer returns the first writable file at or above file in layers.
+// An include comes right before the files that include it, so this is the
+// top-level config or overlay that pulls it in and overrides it.
+func writableLayer(layers []string, file string, writable map[string]bool, path string) string {
+	found := false
+	for _, layer := range layers {
+		found = found || layer == file
+		if found && writable[layer] {
+			return layer
+		}
+	}
+	return path
+}
+
+// patchFile sets the given fields of file, a layer of the config at path,
+// to their values in cfg. An older file is upgraded first, since the fields
+// are written in the current layout.
+func patchFile(file, path string, cfg *Config, fields []string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading config: %w", err)
+	}
+	base := data
+	migrated, _, _, err := migrateLayer(file, path, data)
+	if err != nil {
+		return err
+	}
+	if migrated != nil {
+		base = migrated
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(base, &doc); err != nil {
+		return fmt.Errorf("error parsing %s: %w", file, err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}, HeadComment: doc.HeadComment}
+	}
+
+	for _, field := range fields {
+		value, err := cfg.Value(field)
+		if err != nil {
+			return err
+		}
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", field, err)
+		}
+		setPath(root, strings.Split(field, "."), &node)
+	}
+
+	out, err := encodeYAML(&doc)
+	if err != nil {
+		return err
+	}
+	out = restoreBlankLines(base, out)
+	if err := os.WriteFile(file+".bak", data, 0600); err != nil {
+		return fmt.Errorf("failed to back up config: %w", err)
+	}
+	return writeFileAtomic(file, out, 0600)
+}
+
+// setPath sets the value at keys in a mapping node, adding missing keys at
+// the end of their mapping
+func setPath(mapping *yaml.Node, keys []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != keys[0] {
+			continue
+		}
+		old := mapping.Content[i+1]
+		if len(keys) == 1 {
+			mapping.Content[i+1] = replaceNode(old, value)
+			return
+		}
+		if old.Kind != yaml.MappingNode {
+			mapping.Content[i+1] = replaceNode(old, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		}
+		setPath(mapping.Content[i+1], keys[1:], value)
+		return
+	}
+
+	if len(keys) > 1 {
+		child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setPath(child, keys[1:], value)
+		value = child
+	}
+	// An empty {} would otherwise print its new keys inline
+	mapping.Style &^= yaml.FlowStyle
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[0]}, value)
+}
+
+// replaceNode returns value carrying over the comments of old and, where it
+// still fits, its style, so "quoted" strings and [a, b] lists stay that way
+func replaceNode(old, value *yaml.Node) *yaml.Node {
+	value.HeadComment = old.HeadComment
+	value.LineComment = old.LineComment
+	value.FootComment = old.FootComment
+
+	switch {
+	case old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && value.Tag == "!!str":
+		value.Style = old.Style
+	case old.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && old.Style&yaml.FlowStyle != 0:
+		flow := true
+		for _, item := range value.Content {
+			flow = flow && item.Kind == yaml.ScalarNode
+		}
+		if flow {
+			value.Style = yaml.FlowStyle
+		}
+	}
+	return value
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// restoreBlankLines puts back the blank lines that separated top-level
+// sections in the original file, which the YAML encoder drops
+func restoreBlankLines(original, out []byte) []byte {
+	separated := make(map[string]bool)
+	lines := strings.Split(string(original), "\n")
+	for i, line := range lines {
+		key, ok := topLevelKey(line)
+		if !ok {
+			continue
+		}
+		// Skip the comment block above the key
+		j := i - 1
+		for j >= 0 && strings.HasPrefix(lines[j], "#") {
+			j--
+		}
+		if j >= 0 && strings.TrimSpace(lines[j]) == "" {
+			separated[key] = true
+		}
+	}
+
+	lines = strings.Split(string(out), "\n")
+	result := make([]string, 0, len(lines)+len(separated))
+	for _, line := range lines {
+		if key, ok := topLevelKey(line); ok && separated[key] {
+			// Insert above the comment block that belongs to the key
+			j := len(result)
+			for j > 0 && strings.HasPrefix(result[j-1], "#") {
+				j--
+			}
+			if j > 0 && strings.TrimSpace(result[j-1]) != "" {
+				result = append(result[:j], append([]string{""}, result[j:]...)...)
+			}
+		}
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, "\n"))
+}
+
+// topLevelKey returns the key of an unindented "key:" line
+func topLevelKey(line string) (string, bool) {
+	if line == "" || line[0] == ' ' || line[0] == '#' || line[0] == '-' {
+		return "", false
+	}
+	key, _, ok := strings.Cut(line, ":")
+	return key, ok
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers never see a partial config
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	// Replace the target of a symlinked config, not the link
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLayers creates a config at dir/rvmm.yaml that includes a shared base
+// file and has an overlay, which in turn includes another shared file
+func writeLayers(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.yaml": `version: 2
+github:
+  runner_group: fleet
+  runner_name: fleet
+`,
+		"rvmm.yaml": `version: 2
+include: base.yaml
+github:
+  api_token: ghp_test
+`,
+		"rvmm.d/10-pool.yaml": `include: ../pool.yaml
+options:
+  max_concurrent_runners: 3
+`,
+		"pool.yaml": `vm:
+  display: 1920x1080
+`,
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, files
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSaveLeavesIncludesAlone(t *testing.T) {
+	dir, files := writeLayers(t)
+	path := filepath.Join(dir, "rvmm.yaml")
+
+	cfg, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GitHub.RunnerGroup = "mac-07"
+	cfg.Options.MaxConcurrentRunners = 4
+	cfg.VM.Display = "3840x2160"
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, shared := range []string{"base.yaml", "pool.yaml"} {
+		if got := readFile(t, filepath.Join(dir, shared)); got != files[shared] {
+			t.Errorf("shared %s was changed:\n%s", shared, got)
+		}
+		if _, err := os.Stat(filepath.Join(dir, shared+".bak")); err == nil {
+			t.Errorf("shared %s was backed up", shared)
+		}
+	}
+	if got := readFile(t, path); !strings.Contains(got, "runner_group: mac-07") {
+		t.Errorf("rvmm.yaml does not set the include's field:\n%s", got)
+	}
+	overlay := readFile(t, filepath.Join(dir, "rvmm.d/10-pool.yaml"))
+	for _, want := range []string{"max_concurrent_runners: 4", "display: 3840x2160"} {
+		if !strings.Contains(overlay, want) {
+			t.Errorf("overlay is missing %q:\n%s", want, overlay)
+		}
+	}
+
+	saved, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(cfg, saved); len(diff) > 0 {
+		t.Errorf("reloaded config differs in %v", diff)
+	}
+	if got := saved.GitHub.RunnerName; got != "fleet" {
+		t.Errorf("runner_name = %q, want the include's value", got)
+	}
+}
+
+func TestSaveIncludes(t *testing.T) {
+	dir, files := writeLayers(t)
+	path := filepath.Join(dir, "rvmm.yaml")
+
+	cfg, err := LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.GitHub.RunnerGroup = "mac-07"
+	if err := SaveIncludes(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "base.yaml")); !strings.Contains(got, "runner_group: mac-07") {
+		t.Errorf("base.yaml was not updated:\n%s", got)
+	}
+	if got := readFile(t, path); got != files["rvmm.yaml"] {
+		t.Errorf("rvmm.yaml was changed:\n%s", got)
+	}
+}
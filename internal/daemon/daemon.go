@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// Service managers selectable with daemon.manager
+const (
+	ManagerLaunchd = "launchd"
+	ManagerSystemd = "systemd"
+)
+
+// Service is an rvmm command run in the background by a service manager
+type Service struct {
+	// Name is the launchd label or systemd unit name
+	Name string
+	// Description names the service in messages, e.g. "runner"
+	Description string
+	BinaryPath  string
+	// Args follow the binary, e.g. run --config /etc/rvmm/rvmm.yaml
+	Args             []string
+	User             string
+	WorkingDirectory string
+	StdoutPath       string
+	StderrPath       string
+	// KeepAlive restarts the service whenever it exits
+	KeepAlive bool
+
+	// suffix is appended to the daemon label and service file names
+	suffix string
+}
+
+// Manager installs and controls services with the host's service manager
+type Manager interface {
+	// Name returns the service manager, e.g. "launchd"
+	Name() string
+	// Install writes the service file and starts the service
+	Install(svc Service, out io.Writer) error
+	// Uninstall stops the service and removes its service file
+	Uninstall(svc Service, out io.Writer) error
+	// Status prints whether the service is installed and loaded
+	Status(svc Service, out io.Writer) error
+	// IsRunning reports whether the service is loaded and running
+	IsRunning(svc Service) (bool, error)
+}
+
+// NewManager returns the service manager set in daemon.manager, or the one
+// native to this OS when it is empty, acting on host
+func NewManager(cfg *config.Config, host Host) (Manager, error) {
+	name := cfg.Daemon.Manager
+	if name == "" {
+		switch runtime.GOOS {
+		case "darwin":
+			name = ManagerLaunchd
+		case "linux":
+			name = ManagerSystemd
+		default:
+			return nil, fmt.Errorf("no service manager for %s; set daemon.manager", runtime.GOOS)
+		}
+	}
+
+	switch name {
+	case ManagerLaunchd:
+		return &launchd{cfg: cfg, host: host}, nil
+	case ManagerSystemd:
+		return &systemd{cfg: cfg, host: host}, nil
+	default:
+		return nil, fmt.Errorf("unknown service manager %q", name)
+	}
+}
+
+// runnerService runs `rvmm run` with the given config
+func runnerService(cfg *config.Config, configPath string) (Service, error) {
+	svc, err := newService(cfg, configPath, "", "runner", "run", "--config")
+	if err != nil {
+		return Service{}, err
+	}
+	svc.StdoutPath = filepath.Join(cfg.Options.WorkingDirectory, "stdout")
+	svc.StderrPath = filepath.Join(cfg.Options.WorkingDirectory, "stderr")
+	return svc, nil
+}
+
+// monitorService runs `rvmm monitor`, restarting it whenever it exits
+func monitorService(cfg *config.Config, configPath string) (Service, error) {
+	svc, err := newService(cfg, configPath, ".monitor", "monitor", "monitor", "-config")
+	if err != nil {
+		return Service{}, err
+	}
+	svc.StdoutPath = filepath.Join(cfg.Options.WorkingDirectory, "monitor_stdout.log")
+	svc.StderrPath = filepath.Join(cfg.Options.WorkingDirectory, "monitor_stderr.log")
+	svc.KeepAlive = true
+	return svc, nil
+}
+
+func newService(cfg *config.Config, configPath, suffix, description, command, configFlag string) (Service, error) {
+	svc := Service{
+		Name:             cfg.Daemon.Label + suffix,
+		Description:      description,
+		User:             cfg.Daemon.User,
+		WorkingDirectory: cfg.Options.WorkingDirectory,
+		suffix:           suffix,
+	}
+	if configPath == "" {
+		return svc, nil
+	}
+
+	// Get absolute paths
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return Service{}, fmt.Errorf("failed to get executable path: %w", err)
+	}
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return Service{}, fmt.Errorf("failed to get absolute config path: %w", err)
+	}
+	svc.BinaryPath = binaryPath
+	svc.Args = []string{command, configFlag, absConfigPath}
+	return svc, nil
+}
+
+// Install creates and starts the runner service
+func Install(log *zap.Logger, cfg *config.Config, configPath string, out io.Writer) error {
+	return DefaultHost.Install(log, cfg, configPath, out)
+}
+
+// InstallMonitor creates and starts the log monitor service
+func InstallMonitor(log *zap.Logger, cfg *config.Config, configPath string, out io.Writer) error {
+	return DefaultHost.InstallMonitor(log, cfg, configPath, out)
+}
+
+// Uninstall stops and removes the runner service
+func Uninstall(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	return DefaultHost.Uninstall(log, cfg, out)
+}
+
+// UninstallMonitor stops and removes the log monitor service
+func UninstallMonitor(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	return DefaultHost.UninstallMonitor(log, cfg, out)
+}
+
+// Status shows the current runner service status
+func Status(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	return DefaultHost.Status(log, cfg, out)
+}
+
+// StatusMonitor shows the current log monitor service status
+func StatusMonitor(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	return DefaultHost.StatusMonitor(log, cfg, out)
+}
+
+// IsRunning checks whether the runner service is currently loaded and running
+func IsRunning(cfg *config.Config) (bool, error) {
+	return DefaultHost.IsRunning(cfg)
+}
+
+// Install creates and starts the runner service on h
+func (h Host) Install(log *zap.Logger, cfg *config.Config, configPath string, out io.Writer) error {
+	svc, err := runnerService(cfg, configPath)
+	if err != nil {
+		return err
+	}
+	return h.install(log, cfg, svc, out)
+}
+
+// InstallMonitor creates and starts the log monitor service on h
+func (h Host) InstallMonitor(log *zap.Logger, cfg *config.Config, configPath string, out io.Writer) error {
+	svc, err := monitorService(cfg, configPath)
+	if err != nil {
+		return err
+	}
+	return h.install(log, cfg, svc, out)
+}
+
+// Uninstall stops and removes the runner service on h
+func (h Host) Uninstall(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	svc, err := runnerService(cfg, "")
+	if err != nil {
+		return err
+	}
+	return h.uninstall(log, cfg, svc, out)
+}
+
+// UninstallMonitor stops and removes the log monitor service on h
+func (h Host) UninstallMonitor(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	svc, err := monitorService(cfg, "")
+	if err != nil {
+		return err
+	}
+	return h.uninstall(log, cfg, svc, out)
+}
+
+// Status shows the runner service status on h
+func (h Host) Status(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	svc, err := runnerService(cfg, "")
+	if err != nil {
+		return err
+	}
+	return h.status(cfg, svc, out)
+}
+
+// StatusMonitor shows the log monitor service status on h
+func (h Host) StatusMonitor(log *zap.Logger, cfg *config.Config, out io.Writer) error {
+	svc, err := monitorService(cfg, "")
+	if err != nil {
+		return err
+	}
+	return h.status(cfg, svc, out)
+}
+
+// IsRunning checks whether the runner service is loaded and running on h
+func (h Host) IsRunning(cfg *config.Config) (bool, error) {
+	m, err := NewManager(cfg, h)
+	if err != nil {
+		return false, err
+	}
+	svc, err := runnerService(cfg, "")
+	if err != nil {
+		return false, err
+	}
+	return m.IsRunning(svc)
+}
+
+func (h Host) install(log *zap.Logger, cfg *config.Config, svc Service, out io.Writer) error {
+	m, err := NewManager(cfg, h)
+	if err != nil {
+		return err
+	}
+	log.Info("Installing service", zap.String("manager", m.Name()), zap.String("name", svc.Name))
+
+	// Ensure working directory exists
+	if err := h.mkdirAll(svc.WorkingDirectory, 0755); err != nil {
+		return fmt.Errorf("failed to create working directory: %w", err)
+	}
+
+	if err := m.Install(svc, out); err != nil {
+		return err
+	}
+	log.Info("Service installed", zap.String("manager", m.Name()), zap.String("name", svc.Name))
+
+	// Verify the service is actually running after installation
+	running, err := m.IsRunning(svc)
+	if err != nil {
+		log.Warn("Failed to check service status after install", zap.Error(err))
+	} else if !running {
+		log.Warn("Service was installed but does not appear to be running")
+		fmt.Fprintf(out, "\n⚠️  Warning: the %s was installed but is not currently running.\n", svc.Description)
+	} else {
+		log.Info("Service verified running after install")
+		fmt.Fprintf(out, "\n✅ The %s is running.\n", svc.Description)
+	}
+	return nil
+}
+
+func (h Host) uninstall(log *zap.Logger, cfg *config.Config, svc Service, out io.Writer) error {
+	m, err := NewManager(cfg, h)
+	if err != nil {
+		return err
+	}
+	log.Info("Uninstalling service", zap.String("manager", m.Name()), zap.String("name", svc.Name))
+
+	if err := m.Uninstall(svc, out); err != nil {
+		return err
+	}
+	log.Info("Service uninstalled", zap.String("manager", m.Name()), zap.String("name", svc.Name))
+	return nil
+}
+
+func (h Host) status(cfg *config.Config, svc Service, out io.Writer) error {
+	m, err := NewManager(cfg, h)
+	if err != nil {
+		return err
+	}
+	if err := m.Status(svc, out); err != nil {
+		return err
+	}
+
+	// Check stdout/stderr files
+	if info, err := h.stat(svc.StdoutPath); err == nil {
+		fmt.Fprintf(out, "\nStdout log: %s (%d bytes)\n", svc.StdoutPath, info.Size())
+	}
+	if info, err := h.stat(svc.StderrPath); err == nil {
+		fmt.Fprintf(out, "Stderr log: %s (%d bytes)\n", svc.StderrPath, info.Size())
+	}
+	return nil
+}
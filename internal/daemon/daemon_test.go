@@ -0,0 +1,286 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// fakeRunner records commands and fails the ones listed in fail, keyed by
+// the command line
+type fakeRunner struct {
+	calls []string
+	fail  map[string]string
+}
+
+func (f *fakeRunner) Run(name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, line)
+	if output, ok := f.fail[line]; ok {
+		return []byte(output), errors.New("exit status 1")
+	}
+	return nil, nil
+}
+
+func testHost(t *testing.T) (Host, *fakeRunner) {
+	t.Helper()
+	runner := &fakeRunner{fail: map[string]string{}}
+	return Host{Runner: runner, Root: t.TempDir()}, runner
+}
+
+func testConfig(manager string) *config.Config {
+	cfg := config.Default()
+	cfg.Daemon.Manager = manager
+	cfg.Options.WorkingDirectory = "/var/lib/rvmm"
+	return cfg
+}
+
+func readRooted(t *testing.T, h Host, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(h.Root, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func assertCalls(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands:\n  got  %q\n  want %q", got, want)
+	}
+}
+
+func assertContains(t *testing.T, name, got string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("%s missing %q:\n%s", name, w, got)
+		}
+	}
+}
+
+func TestLaunchdInstallUninstall(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerLaunchd)
+	plist := "/Library/LaunchDaemons/com.mirego.ekiden.plist"
+	rooted := filepath.Join(h.Root, plist)
+
+	var out bytes.Buffer
+	if err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &out); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, "plist", readRooted(t, h, plist),
+		"<string>com.mirego.ekiden</string>",
+		"<string>run</string>\n      <string>--config</string>\n      <string>/etc/rvmm/rvmm.yaml</string>",
+		"<key>UserName</key>\n    <string>admin</string>",
+		"<string>/var/lib/rvmm/stdout</string>",
+		"<key>LaunchOnlyOnce</key>",
+	)
+	assertCalls(t, runner.calls,
+		"launchctl bootstrap system "+rooted,
+		"launchctl print system/com.mirego.ekiden",
+	)
+	assertContains(t, "output", out.String(), "LaunchDaemon installed", "Plist location: "+plist, "runner is running")
+	if _, err := os.Stat(filepath.Join(h.Root, "/var/lib/rvmm")); err != nil {
+		t.Errorf("working directory not created under root: %v", err)
+	}
+
+	runner.calls = nil
+	if err := h.Uninstall(zap.NewNop(), cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, runner.calls, "launchctl bootout system "+rooted)
+	if _, err := os.Stat(rooted); !os.IsNotExist(err) {
+		t.Errorf("plist not removed: %v", err)
+	}
+
+	// Nothing to boot out once the plist is gone
+	runner.calls = nil
+	out.Reset()
+	if err := h.Uninstall(zap.NewNop(), cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, runner.calls)
+	assertContains(t, "output", out.String(), "is not installed")
+}
+
+func TestLaunchdMonitorKeepsAlive(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerLaunchd)
+	plist := "/Library/LaunchDaemons/com.mirego.ekiden.monitor.plist"
+
+	if err := h.InstallMonitor(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	data := readRooted(t, h, plist)
+	assertContains(t, "plist", data,
+		"<string>com.mirego.ekiden.monitor</string>",
+		"<string>monitor</string>\n      <string>-config</string>",
+		"<key>KeepAlive</key>",
+		"monitor_stderr.log",
+	)
+	if strings.Contains(data, "LaunchOnlyOnce") {
+		t.Errorf("monitor plist sets LaunchOnlyOnce:\n%s", data)
+	}
+	assertCalls(t, runner.calls,
+		"launchctl bootstrap system "+filepath.Join(h.Root, plist),
+		"launchctl print system/com.mirego.ekiden.monitor",
+	)
+}
+
+func TestLaunchdUserAgentDomain(t *testing.T) {
+	h, runner := testHost(t)
+	t.Setenv("HOME", "/Users/ci")
+	cfg := testConfig(ManagerLaunchd)
+	cfg.Daemon.PlistPath = "~/Library/LaunchAgents/com.mirego.ekiden.plist"
+
+	var out bytes.Buffer
+	if err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &out); err != nil {
+		t.Fatal(err)
+	}
+	domain := fmt.Sprintf("gui/%d", os.Getuid())
+	assertCalls(t, runner.calls,
+		"launchctl bootstrap "+domain+" "+filepath.Join(h.Root, "/Users/ci/Library/LaunchAgents/com.mirego.ekiden.plist"),
+		"launchctl print "+domain+"/com.mirego.ekiden",
+	)
+	assertContains(t, "output", out.String(), "LaunchAgent installed", "on user login")
+}
+
+func TestLaunchdBootstrapFailure(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerLaunchd)
+	rooted := filepath.Join(h.Root, cfg.Daemon.PlistPath)
+	runner.fail["launchctl bootstrap system "+rooted] = "Bootstrap failed: 5: Input/output error"
+
+	err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "Input/output error") {
+		t.Fatalf("Install() error = %v, want the launchctl output", err)
+	}
+	assertCalls(t, runner.calls, "launchctl bootstrap system "+rooted)
+}
+
+func TestLaunchdStatus(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerLaunchd)
+	if err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.Root, "/var/lib/rvmm/stdout"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.calls = nil
+	runner.fail["launchctl print system/com.mirego.ekiden"] = "Could not find service"
+	var out bytes.Buffer
+	if err := h.Status(zap.NewNop(), cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, runner.calls, "launchctl print system/com.mirego.ekiden")
+	assertContains(t, "output", out.String(), "Status: Not loaded", "Stdout log: /var/lib/rvmm/stdout (5 bytes)")
+}
+
+func TestSystemdInstallUninstall(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerSystemd)
+	cfg.Daemon.Label = "rvmm"
+	unit := "/etc/systemd/system/rvmm.service"
+
+	var out bytes.Buffer
+	if err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/my config.yaml", &out); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, "unit", readRooted(t, h, unit),
+		"Description=rvmm runner (rvmm)",
+		` run --config "/etc/rvmm/my config.yaml"`,
+		"User=admin",
+		"WorkingDirectory=/var/lib/rvmm",
+		"StandardOutput=append:/var/lib/rvmm/stdout",
+		"Restart=no",
+	)
+	assertCalls(t, runner.calls,
+		"systemctl daemon-reload",
+		"systemctl enable --now rvmm.service",
+		"systemctl is-active --quiet rvmm.service",
+	)
+	assertContains(t, "output", out.String(), "Unit location: "+unit, "runner is running")
+
+	runner.calls = nil
+	if err := h.Uninstall(zap.NewNop(), cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, runner.calls,
+		"systemctl disable --now rvmm.service",
+		"systemctl daemon-reload",
+	)
+	if _, err := os.Stat(filepath.Join(h.Root, unit)); !os.IsNotExist(err) {
+		t.Errorf("unit not removed: %v", err)
+	}
+}
+
+func TestSystemdMonitorRestarts(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerSystemd)
+	cfg.Daemon.Label = "rvmm"
+
+	if err := h.InstallMonitor(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, "unit", readRooted(t, h, "/etc/systemd/system/rvmm.monitor.service"),
+		" monitor -config /etc/rvmm/rvmm.yaml",
+		"Restart=always",
+	)
+	assertCalls(t, runner.calls,
+		"systemctl daemon-reload",
+		"systemctl enable --now rvmm.monitor.service",
+		"systemctl is-active --quiet rvmm.monitor.service",
+	)
+}
+
+func TestSystemdNotRunningAfterInstall(t *testing.T) {
+	h, runner := testHost(t)
+	cfg := testConfig(ManagerSystemd)
+	cfg.Daemon.Label = "rvmm"
+	runner.fail["systemctl is-active --quiet rvmm.service"] = ""
+
+	var out bytes.Buffer
+	if err := h.Install(zap.NewNop(), cfg, "/etc/rvmm/rvmm.yaml", &out); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, "output", out.String(), "installed but is not currently running")
+
+	running, err := h.IsRunning(cfg)
+	if err != nil || running {
+		t.Errorf("IsRunning() = %v, %v; want false", running, err)
+	}
+}
+
+func TestNewManagerRejectsUnknown(t *testing.T) {
+	if _, err := NewManager(testConfig("upstart"), Host{}); err == nil {
+		t.Fatal("NewManager() accepted an unknown service manager")
+	}
+}
+
+func TestQuoteUnitArg(t *testing.T) {
+	tests := map[string]string{
+		"/usr/local/bin/rvmm": "/usr/local/bin/rvmm",
+		"":                    `""`,
+		"my config.yaml":      `"my config.yaml"`,
+		`a"b`:                 `"a\"b"`,
+		"$HOME":               `"$$HOME"`,
+		"100%":                `"100%%"`,
+	}
+	for in, want := range tests {
+		if got := quoteUnitArg(in); got != want {
+			t.Errorf("quoteUnitArg(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// CommandRunner runs service manager commands such as launchctl and
+// systemctl
+type CommandRunner interface {
+	// Run runs name with args and returns its combined output
+	Run(name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands on the host
+type ExecRunner struct{}
+
+// Run runs the command with os/exec
+func (ExecRunner) Run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// Host is everything the package touches outside the process: the commands
+// it runs and the files it writes. Tests can script the commands and point
+// Root at a temporary directory.
+type Host struct {
+	Runner CommandRunner
+	// Root is prepended to every path the package reads or writes, e.g.
+	// /Library/LaunchDaemons/x.plist becomes <Root>/Library/LaunchDaemons/x.plist.
+	// Paths inside generated files are not changed. Empty means /.
+	Root string
+}
+
+// DefaultHost runs real commands against the real filesystem
+var DefaultHost = Host{Runner: ExecRunner{}}
+
+// path maps a path on the host to where the package accesses it
+func (h Host) path(p string) string {
+	if h.Root == "" {
+		return p
+	}
+	return filepath.Join(h.Root, p)
+}
+
+func (h Host) run(name string, args ...string) ([]byte, error) {
+	return h.Runner.Run(name, args...)
+}
+
+func (h Host) writeFile(p string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(h.path(p), data, perm)
+}
+
+func (h Host) mkdirAll(p string, perm os.FileMode) error {
+	return os.MkdirAll(h.path(p), perm)
+}
+
+func (h Host) remove(p string) error {
+	return os.Remove(h.path(p))
+}
+
+func (h Host) stat(p string) (os.FileInfo, error) {
+	return os.Stat(h.path(p))
+}
@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/rxtech-lab/rvmm/assets"
+	"github.com/rxtech-lab/rvmm/internal/config"
+)
+
+// UnitData contains data for the systemd unit template
+type UnitData struct {
+	Name             string
+	Description      string
+	ExecStart        string
+	User             string
+	WorkingDirectory string
+	StdoutPath       string
+	StderrPath       string
+	KeepAlive        bool
+}
+
+// systemd manages services as system units in daemon.unit_dir
+type systemd struct {
+	cfg  *config.Config
+	host Host
+}
+
+func (s *systemd) Name() string {
+	return ManagerSystemd
+}
+
+// Install writes the unit, reloads systemd and enables and starts the unit
+func (s *systemd) Install(svc Service, out io.Writer) error {
+	unitPath := s.unitPath(svc)
+	data, err := renderUnit(svc)
+	if err != nil {
+		return err
+	}
+
+	if err := s.host.mkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+		return fmt.Errorf("failed to create unit directory: %w", err)
+	}
+	if err := s.host.writeFile(unitPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write unit (try with sudo): %w", err)
+	}
+
+	if err := s.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := s.systemctl("enable", "--now", unitName(svc)); err != nil {
+		return fmt.Errorf("failed to start %s: %w", svc.Description, err)
+	}
+
+	fmt.Fprintf(out, "systemd unit installed: %s\n", unitName(svc))
+	fmt.Fprintf(out, "Unit location: %s\n", unitPath)
+	fmt.Fprintf(out, "\nThe %s will start automatically on boot.\n", svc.Description)
+	return nil
+}
+
+// Uninstall disables and stops the unit and removes it
+func (s *systemd) Uninstall(svc Service, out io.Writer) error {
+	unitPath := s.unitPath(svc)
+	if _, err := s.host.stat(unitPath); os.IsNotExist(err) {
+		fmt.Fprintf(out, "systemd unit %s is not installed\n", unitName(svc))
+		return nil
+	}
+
+	// The unit may already be stopped
+	if err := s.systemctl("disable", "--now", unitName(svc)); err != nil {
+		fmt.Fprintf(out, "systemctl disable failed (may already be stopped): %v\n", err)
+	}
+	if err := s.host.remove(unitPath); err != nil {
+		return fmt.Errorf("failed to remove unit (try with sudo): %w", err)
+	}
+	if err := s.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "systemd unit %s uninstalled\n", unitName(svc))
+	return nil
+}
+
+// Status prints the unit location and `systemctl status` output
+func (s *systemd) Status(svc Service, out io.Writer) error {
+	unitPath := s.unitPath(svc)
+	if _, err := s.host.stat(unitPath); os.IsNotExist(err) {
+		fmt.Fprintf(out, "systemd unit %s is not installed\n", unitName(svc))
+		return nil
+	}
+
+	fmt.Fprintf(out, "systemd unit: %s\n", unitName(svc))
+	fmt.Fprintf(out, "Unit path: %s\n", unitPath)
+
+	// systemctl status exits non-zero for inactive units but still reports them
+	output, _ := s.host.run("systemctl", "status", "--no-pager", unitName(svc))
+	running, _ := s.IsRunning(svc)
+	if running {
+		fmt.Fprintln(out, "Status: Active")
+	} else {
+		fmt.Fprintln(out, "Status: Not active")
+	}
+	fmt.Fprintf(out, "\n%s", string(output))
+	return nil
+}
+
+// IsRunning reports whether the unit is active
+func (s *systemd) IsRunning(svc Service) (bool, error) {
+	if _, err := s.host.run("systemctl", "is-active", "--quiet", unitName(svc)); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *systemd) unitPath(svc Service) string {
+	return filepath.Join(s.cfg.Daemon.UnitDir, unitName(svc))
+}
+
+func unitName(svc Service) string {
+	return svc.Name + ".service"
+}
+
+func (s *systemd) systemctl(args ...string) error {
+	output, err := s.host.run("systemctl", args...)
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %w\nOutput: %s", strings.Join(args, " "), err, string(output))
+	}
+	return nil
+}
+
+func renderUnit(svc Service) ([]byte, error) {
+	command := append([]string{svc.BinaryPath}, svc.Args...)
+	for i, arg := range command {
+		command[i] = quoteUnitArg(arg)
+	}
+
+	data := UnitData{
+		Name:             svc.Name,
+		Description:      svc.Description,
+		ExecStart:        strings.Join(command, " "),
+		User:             svc.User,
+		WorkingDirectory: svc.WorkingDirectory,
+		StdoutPath:       svc.StdoutPath,
+		StderrPath:       svc.StderrPath,
+		KeepAlive:        svc.KeepAlive,
+	}
+
+	tmpl, err := template.New("unit").Parse(string(assets.SystemdUnit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse unit template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute unit template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteUnitArg quotes an ExecStart argument that systemd would otherwise
+// split or expand
+func quoteUnitArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$%;") {
+		return arg
+	}
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	arg = strings.ReplaceAll(arg, "$", "$$")
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	return `"` + arg + `"`
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// RunBootstrap executes the configured vm.bootstrap steps in order.
+// It stops at the first step that exits non-zero.
+func (s *SSHClient) RunBootstrap(ctx context.Context, ip string) error {
+	steps := s.cfg.VM.Bootstrap
+	if len(steps) == 0 {
+		return nil
+	}
+
+	s.log.Info("Running guest bootstrap", zap.Int("steps", len(steps)))
+
+	for i, step := range steps {
+		name := bootstrapStepName(i, step)
+
+		script, err := loadBootstrapScript(step)
+		if err != nil {
+			return fmt.Errorf("bootstrap step %s: %w", name, err)
+		}
+
+		s.log.Info("Running bootstrap step", zap.String("step", name))
+		output, err := s.RunScript(ctx, ip, script, step.Env)
+		if err != nil {
+			s.log.Error("Bootstrap step failed",
+				zap.String("step", name),
+				zap.String("output", output),
+				zap.Error(err))
+			return fmt.Errorf("bootstrap step %s failed: %w (output: %s)", name, err, output)
+		}
+		s.log.Debug("Bootstrap step output", zap.String("step", name), zap.String("output", output))
+	}
+
+	s.log.Info("Guest bootstrap completed")
+	return nil
+}
+
+// RunScript pipes a shell script to bash on the VM with the given NAME=value
+// environment variables exported, and returns the combined output.
+func (s *SSHClient) RunScript(ctx context.Context, ip string, script string, env []string) (string, error) {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(value))
+	}
+	b.WriteString(script)
+	b.WriteString("\n")
+
+	cmd := s.command(ctx, ip, "/bin/bash -l -s", "-T")
+	cmd.Stdin = strings.NewReader(b.String())
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("SSH script failed: %w", err)
+	}
+
+	return string(output), nil
+}
+
+func loadBootstrapScript(step config.BootstrapStep) (string, error) {
+	if step.Script != "" {
+		return step.Script, nil
+	}
+	data, err := os.ReadFile(step.File)
+	if err != nil {
+		return "", fmt.Errorf("failed to read script file: %w", err)
+	}
+	return string(data), nil
+}
+
+func bootstrapStepName(index int, step config.BootstrapStep) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	if step.File != "" {
+		return step.File
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// shellQuote wraps a value in single quotes for safe use in a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"github.com/rxtech-lab/rvmm/internal/setup"
+	"go.uber.org/zap"
+)
+
+// CapacityStatus describes the current concurrency and where it comes from
+type CapacityStatus struct {
+	MaxConcurrentRunners int    `json:"max_concurrent_runners"`
+	ActiveRunners        int    `json:"active_runners"`
+	Override             *int   `json:"override,omitempty"`
+	ScheduleWindow       string `json:"schedule_window,omitempty"`
+}
+
+// Capacity decides how many runner slots the pool should have. A runtime
+// override wins over the schedule, which wins over
+// options.max_concurrent_runners.
+type Capacity struct {
+	log   *zap.Logger
+	slots *SlotPool
+
+	mu       sync.Mutex
+	cfg      *config.Config
+	override *int
+	window   string
+}
+
+// NewCapacity creates a controller and sizes slots for the current time
+func NewCapacity(cfg *config.Config, log *zap.Logger, slots *SlotPool) *Capacity {
+	c := &Capacity{
+		log:   log.With(zap.String("component", "capacity")),
+		slots: slots,
+		cfg:   cfg,
+	}
+	c.mu.Lock()
+	c.apply(time.Now())
+	c.mu.Unlock()
+	return c
+}
+
+// Run re-evaluates the schedule until ctx is cancelled
+func (c *Capacity) Run(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			c.apply(now)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// SetOverride pins the concurrency until ClearOverride is called
+func (c *Capacity) SetOverride(n int) error {
+	if n < 0 {
+		return fmt.Errorf("max_concurrent_runners must not be negative")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg.VM.CPU > 0 || c.cfg.VM.MemoryMB > 0 {
+		cpus, memoryMB, err := setup.HostCapacity()
+		if err != nil {
+			c.log.Warn("Failed to read host capacity, skipping check", zap.Error(err))
+		} else {
+			check := *c.cfg
+			check.Options.MaxConcurrentRunners = n
+			check.Options.Schedule = nil
+			if err := check.ValidateCapacity(cpus, memoryMB); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.override = &n
+	c.log.Info("Concurrency override set", zap.Int("max_concurrent_runners", n))
+	c.apply(time.Now())
+	return nil
+}
+
+// ClearOverride returns control to the schedule and config
+func (c *Capacity) ClearOverride() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.override == nil {
+		return
+	}
+	c.override = nil
+	c.log.Info("Concurrency override cleared")
+	c.apply(time.Now())
+}
+
+// SetConfig applies a reloaded max_concurrent_runners and schedule
+func (c *Capacity) SetConfig(cfg *config.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+	c.apply(time.Now())
+}
+
+// Status reports the current concurrency
+func (c *Capacity) Status() CapacityStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := CapacityStatus{
+		MaxConcurrentRunners: c.slots.Size(),
+		ActiveRunners:        c.slots.Active(),
+		ScheduleWindow:       c.window,
+	}
+	if c.override != nil {
+		n := *c.override
+		status.Override = &n
+	}
+	return status
+}
+
+// apply resizes the slot pool when the desired concurrency changed; c.mu
+// must be held
+func (c *Capacity) apply(now time.Time) {
+	desired, window := c.cfg.DesiredRunners(now)
+	if c.override != nil {
+		desired = *c.override
+	}
+	if window == c.window && desired == c.slots.Size() {
+		return
+	}
+
+	if window != c.window {
+		c.log.Info("Schedule window changed", zap.String("schedule_window", window))
+		c.window = window
+	}
+	if desired != c.slots.Size() {
+		c.log.Info("Resizing slot pool",
+			zap.Int("from", c.slots.Size()),
+			zap.Int("to", desired),
+			zap.Int("active_runners", c.slots.Active()),
+		)
+		c.slots.Resize(desired)
+	}
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// ConcurrencyRequest is the body of PUT /v1/concurrency
+type ConcurrencyRequest struct {
+	MaxConcurrentRunners int `json:"max_concurrent_runners"`
+}
+
+// ControlServer exposes runtime controls on a Unix socket that only the
+// owning user can connect to
+type ControlServer struct {
+	path     string
+	log      *zap.Logger
+	listener net.Listener
+	capacity *Capacity
+}
+
+// NewControlServer creates a control server for options.control_socket
+func NewControlServer(cfg *config.Config, log *zap.Logger) *ControlServer {
+	return &ControlServer{
+		path: ControlSocketPath(cfg),
+		log:  log.With(zap.String("component", "control")),
+	}
+}
+
+// ControlSocketPath resolves options.control_socket against the working
+// directory. It returns an empty string when the socket is disabled.
+func ControlSocketPath(cfg *config.Config) string {
+	path := cfg.Options.ControlSocket
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfg.Options.WorkingDirectory, path)
+}
+
+// Listen claims the socket. A stale socket left by a crashed process is
+// replaced, but Listen fails when the path is not a socket or another runner
+// still answers on it.
+func (s *ControlServer) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if err := removeStaleSocket(s.path); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(s.path, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Close releases the socket; closing the listener removes the socket file
+func (s *ControlServer) Close() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// Serve answers requests for capacity on the socket claimed by Listen until
+// ctx is cancelled
+func (s *ControlServer) Serve(ctx context.Context, capacity *Capacity) error {
+	if s.listener == nil {
+		return fmt.Errorf("control socket is not listening")
+	}
+	s.capacity = capacity
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/concurrency", s.handleConcurrency)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	s.log.Info("Control socket listening", zap.String("path", s.path))
+	if err := server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// removeStaleSocket removes a socket nothing answers on
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check control socket: %w", err)
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("control socket path %s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("another runner is already listening on control socket %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+	return nil
+}
+
+func (s *ControlServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.capacity.Status())
+}
+
+func (s *ControlServer) handleConcurrency(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		var req ConcurrencyRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.capacity.SetOverride(req.MaxConcurrentRunners); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		s.capacity.ClearOverride()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.capacity.Status())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ControlClient talks to a running runner over its control socket
+type ControlClient struct {
+	http *http.Client
+}
+
+// NewControlClient creates a client for the socket configured in cfg
+func NewControlClient(cfg *config.Config) (*ControlClient, error) {
+	path := ControlSocketPath(cfg)
+	if path == "" {
+		return nil, fmt.Errorf("options.control_socket is disabled")
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	return &ControlClient{
+		http: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}, nil
+}
+
+// Status returns the runner's current concurrency
+func (c *ControlClient) Status() (*CapacityStatus, error) {
+	return c.do(http.MethodGet, "/v1/status", nil)
+}
+
+// SetConcurrency overrides the runner's concurrency
+func (c *ControlClient) SetConcurrency(n int) (*CapacityStatus, error) {
+	return c.do(http.MethodPut, "/v1/concurrency", ConcurrencyRequest{MaxConcurrentRunners: n})
+}
+
+// ResetConcurrency clears a concurrency override
+func (c *ControlClient) ResetConcurrency() (*CapacityStatus, error) {
+	return c.do(http.MethodDelete, "/v1/concurrency", nil)
+}
+
+func (c *ControlClient) do(method, path string, body any) (*CapacityStatus, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, "http://rvmm"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach runner (is it running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("runner rejected request: %s", bytes.TrimSpace(msg))
+	}
+
+	var status CapacityStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &status, nil
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// controlTestConfig returns a config whose control socket path is short
+// enough for the Unix socket limit on every platform
+func controlTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "rvmm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := testConfig(t)
+	cfg.Options.ControlSocket = filepath.Join(dir, "control.sock")
+	return cfg
+}
+
+// serveControl starts a control server for capacity and stops it with the test
+func serveControl(t *testing.T, cfg *config.Config, capacity *Capacity) {
+	t.Helper()
+	server := NewControlServer(cfg, zap.NewNop())
+	if err := server.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Serve(ctx, capacity)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		server.Close()
+	})
+}
+
+func TestControlProtocol(t *testing.T) {
+	cfg := controlTestConfig(t)
+	cfg.Options.MaxConcurrentRunners = 1
+	cfg.Options.Schedule = []config.ScheduleWindow{{Name: "always", Start: "00:00", End: "24:00", MaxConcurrentRunners: 2}}
+	capacity := NewCapacity(cfg, zap.NewNop(), NewSlotPool(0))
+	serveControl(t, cfg, capacity)
+
+	client, err := NewControlClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(step string, status *CapacityStatus, err error, max int, override bool) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if status.MaxConcurrentRunners != max || (status.Override != nil) != override || status.ScheduleWindow != "always" {
+			t.Errorf("%s: status = %+v, want max %d, override %v, window always", step, *status, max, override)
+		}
+	}
+
+	status, err := client.Status()
+	check("get", status, err, 2, false)
+
+	// An override wins over the active schedule window
+	status, err = client.SetConcurrency(5)
+	check("set", status, err, 5, true)
+	if *status.Override != 5 {
+		t.Errorf("override = %d, want 5", *status.Override)
+	}
+
+	if _, err := client.SetConcurrency(-1); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("set -1: error = %v, want it rejected", err)
+	}
+	status, err = client.Status()
+	check("get after rejected set", status, err, 5, true)
+
+	// Resetting hands control back to the schedule
+	status, err = client.ResetConcurrency()
+	check("reset", status, err, 2, false)
+}
+
+func TestControlProtocolRejectsBadRequests(t *testing.T) {
+	cfg := controlTestConfig(t)
+	serveControl(t, cfg, NewCapacity(cfg, zap.NewNop(), NewSlotPool(0)))
+	client, err := NewControlClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/v1/status", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/concurrency", "", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/v1/concurrency", "three", http.StatusBadRequest},
+		{http.MethodGet, "/v1/unknown", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "http://rvmm"+tt.path, strings.NewReader(tt.body))
+		resp, err := client.http.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestControlListenReplacesStaleSocket(t *testing.T) {
+	cfg := controlTestConfig(t)
+	path := ControlSocketPath(cfg)
+	// A crashed runner leaves its socket behind with nothing listening
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	server := NewControlServer(cfg, zap.NewNop())
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	server.Close()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left behind after Close, stat error = %v", err)
+	}
+}
+
+func TestControlListenRefusesRunningRunner(t *testing.T) {
+	cfg := controlTestConfig(t)
+	serveControl(t, cfg, NewCapacity(cfg, zap.NewNop(), NewSlotPool(0)))
+
+	second := NewControlServer(cfg, zap.NewNop())
+	if err := second.Listen(); err == nil || !strings.Contains(err.Error(), "already listening") {
+		second.Close()
+		t.Fatalf("second Listen: error = %v, want it refused", err)
+	}
+
+	// The first runner keeps its socket
+	client, err := NewControlClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Status(); err != nil {
+		t.Errorf("first runner no longer answers: %v", err)
+	}
+}
+
+func TestControlListenRefusesNonSocket(t *testing.T) {
+	cfg := controlTestConfig(t)
+	path := ControlSocketPath(cfg)
+	if err := os.WriteFile(path, []byte("not a socket\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewControlServer(cfg, zap.NewNop())
+	if err := server.Listen(); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		server.Close()
+		t.Fatalf("Listen: error = %v, want it refused", err)
+	}
+	if got := readTestFile(t, path); got != "not a socket\n" {
+		t.Errorf("file at the socket path was changed to %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// diagnosticsTimeout bounds the total time spent collecting a bundle
+const diagnosticsTimeout = 2 * time.Minute
+
+// DiagnosticsCollector records the host-side transcript of a run and, on
+// failure, packs it together with guest logs into a tarball.
+type DiagnosticsCollector struct {
+	cfg        *config.Config
+	log        *zap.Logger
+	transcript *lockedBuffer
+}
+
+// NewDiagnosticsCollector creates a collector and returns a logger that also
+// writes every entry (including debug) to the collector's transcript.
+func NewDiagnosticsCollector(cfg *config.Config, log *zap.Logger) (*DiagnosticsCollector, *zap.Logger) {
+	buf := &lockedBuffer{}
+	encoderCfg := zap.NewDevelopmentEncoderConfig()
+	transcriptCore := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(encoderCfg),
+		zapcore.AddSync(buf),
+		zapcore.DebugLevel,
+	)
+	teeLog := zap.New(zapcore.NewTee(log.Core(), transcriptCore))
+
+	return &DiagnosticsCollector{
+		cfg:        cfg,
+		log:        log,
+		transcript: buf,
+	}, teeLog
+}
+
+// Collect writes a diagnostics bundle for a failed run and returns its path.
+// ip may be empty when the VM never became reachable, in which case only
+// host-side data is included.
+func (d *DiagnosticsCollector) Collect(ctx context.Context, ssh *SSHClient, instanceName, ip string, runErr error) (string, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	dir := d.cfg.Options.Diagnostics.Directory
+	if dir == "" {
+		dir = "diagnostics"
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(d.cfg.Options.WorkingDirectory, dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+
+	bundlePath := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", instanceName, time.Now().UTC().Format("20060102-150405")))
+	d.log.Info("Collecting failure diagnostics", zap.String("bundle", bundlePath))
+
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create diagnostics bundle: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	if runErr != nil {
+		addTarFile(tw, "error.txt", []byte(runErr.Error()+"\n"))
+	}
+
+	if ip != "" && ssh != nil {
+		if err := d.collectRunnerDiag(ctx, ssh, ip, tw); err != nil {
+			d.log.Warn("Failed to collect runner _diag logs", zap.Error(err))
+			addTarFile(tw, "guest/_diag.error.txt", []byte(err.Error()+"\n"))
+		}
+
+		logCmd := fmt.Sprintf("log show --last %dm --style compact", d.systemLogMinutes())
+		if output, err := ssh.ExecuteWithOutput(ctx, ip, logCmd); err != nil {
+			d.log.Warn("Failed to collect guest system log", zap.Error(err))
+			addTarFile(tw, "guest/system.log.error.txt", []byte(err.Error()+"\n"+output))
+		} else {
+			addTarFile(tw, "guest/system.log", []byte(output))
+		}
+	}
+
+	// Add the transcript last so it includes the collection steps above
+	addTarFile(tw, "host/transcript.log", d.transcript.Bytes())
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize diagnostics bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize diagnostics bundle: %w", err)
+	}
+
+	d.log.Info("Failure diagnostics written", zap.String("bundle", bundlePath))
+	return bundlePath, nil
+}
+
+// collectRunnerDiag streams the runner _diag directory from the guest and
+// copies its entries into the bundle under guest/.
+func (d *DiagnosticsCollector) collectRunnerDiag(ctx context.Context, ssh *SSHClient, ip string, tw *tar.Writer) error {
+	output, err := ssh.ExecuteStdout(ctx, ip, "tar -cf - -C ./actions-runner _diag")
+	if err != nil {
+		return fmt.Errorf("guest tar failed: %w", err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(output))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read guest tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		addTarFile(tw, path.Join("guest", hdr.Name), data)
+	}
+}
+
+func (d *DiagnosticsCollector) systemLogMinutes() int {
+	window, err := time.ParseDuration(d.cfg.Options.Diagnostics.SystemLogWindow)
+	if err != nil || window <= 0 {
+		window = 15 * time.Minute
+	}
+	return int(math.Ceil(window.Minutes()))
+}
+
+func addTarFile(tw *tar.Writer, name string, data []byte) {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return
+	}
+	_, _ = tw.Write(data)
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes from zap
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
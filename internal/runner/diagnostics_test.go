@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeGuest stands in for sshpass: it streams a _diag directory for tar and
+// prints a line for log show
+func fakeGuest(t *testing.T, tarExit int) {
+	t.Helper()
+	guest := t.TempDir()
+	diag := filepath.Join(guest, "_diag")
+	if err := os.MkdirAll(diag, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(diag, "Runner_1.log"), []byte("runner log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("Runner_1.log", filepath.Join(diag, "latest.log")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GUEST", guest)
+	t.Setenv("TAR_EXIT", strconv.Itoa(tarExit))
+	fakeCommand(t, "sshpass", `for last; do :; done
+case "$last" in
+tar*)
+	[ "$TAR_EXIT" = 0 ] || { echo "no _diag" >&2; exit "$TAR_EXIT"; }
+	tar -cf - -C "$GUEST" _diag ;;
+"log show"*) echo "system log: $last" ;;
+*) exit 1 ;;
+esac
+`)
+}
+
+// readBundle returns the names and contents of the files in a bundle, in order
+func readBundle(t *testing.T, path string) ([]string, map[string]string) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	contents := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names, contents
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		names = append(names, hdr.Name)
+		contents[hdr.Name] = string(data)
+	}
+}
+
+func TestDiagnosticsCollect(t *testing.T) {
+	fakeGuest(t, 0)
+	cfg := testConfig(t)
+	cfg.Options.Diagnostics.SystemLogWindow = "90s"
+	diag, log := NewDiagnosticsCollector(cfg, zap.NewNop())
+	log.Debug("booting runner_0")
+
+	bundle, err := diag.Collect(context.Background(), NewSSHClient(cfg, log), "runner_0", "192.168.64.2", errors.New("runner exited"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir := filepath.Join(cfg.Options.WorkingDirectory, "diagnostics"); filepath.Dir(bundle) != dir {
+		t.Errorf("bundle written to %s, want %s", filepath.Dir(bundle), dir)
+	}
+	if !regexp.MustCompile(`^runner_0-\d{8}-\d{6}\.tar\.gz$`).MatchString(filepath.Base(bundle)) {
+		t.Errorf("bundle name %q does not match <instance>-<timestamp>.tar.gz", filepath.Base(bundle))
+	}
+
+	names, contents := readBundle(t, bundle)
+	want := "error.txt guest/_diag/Runner_1.log guest/system.log host/transcript.log"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("bundle files = %s, want %s", got, want)
+	}
+	if contents["error.txt"] != "runner exited\n" {
+		t.Errorf("error.txt = %q", contents["error.txt"])
+	}
+	if contents["guest/_diag/Runner_1.log"] != "runner log\n" {
+		t.Errorf("Runner_1.log = %q", contents["guest/_diag/Runner_1.log"])
+	}
+	if want := "system log: log show --last 2m --style compact\n"; contents["guest/system.log"] != want {
+		t.Errorf("system.log = %q, want %q", contents["guest/system.log"], want)
+	}
+	// The transcript is added last, so it covers the guest collection
+	transcript := contents["host/transcript.log"]
+	if !strings.Contains(transcript, "booting runner_0") || !strings.Contains(transcript, "log show") {
+		t.Errorf("transcript is missing entries:\n%s", transcript)
+	}
+}
+
+func TestDiagnosticsCollectGuestErrors(t *testing.T) {
+	fakeGuest(t, 2)
+	cfg := testConfig(t)
+	cfg.Options.Diagnostics.Directory = t.TempDir()
+	diag, log := NewDiagnosticsCollector(cfg, zap.NewNop())
+
+	bundle, err := diag.Collect(context.Background(), NewSSHClient(cfg, log), "runner_1", "192.168.64.3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(bundle) != cfg.Options.Diagnostics.Directory {
+		t.Errorf("bundle written to %s, want %s", filepath.Dir(bundle), cfg.Options.Diagnostics.Directory)
+	}
+
+	names, contents := readBundle(t, bundle)
+	want := "guest/_diag.error.txt guest/system.log host/transcript.log"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("bundle files = %s, want %s", got, want)
+	}
+	if !strings.Contains(contents["guest/_diag.error.txt"], "no _diag") {
+		t.Errorf("_diag.error.txt = %q, want the guest's stderr", contents["guest/_diag.error.txt"])
+	}
+}
+
+func TestDiagnosticsCollectWithoutIP(t *testing.T) {
+	// Any ssh call would fail the bundle layout check below
+	fakeCommand(t, "sshpass", "exit 1\n")
+	cfg := testConfig(t)
+	diag, log := NewDiagnosticsCollector(cfg, zap.NewNop())
+
+	bundle, err := diag.Collect(context.Background(), NewSSHClient(cfg, log), "runner_0", "", errors.New("no IP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := readBundle(t, bundle)
+	if got := strings.Join(names, " "); got != "error.txt host/transcript.log" {
+		t.Errorf("bundle files = %s, want only host-side data", got)
+	}
+}
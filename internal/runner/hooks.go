@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// Hook stages passed to hook executables as RVMM_HOOK_STAGE
+const (
+	HookAfterClone   = "after_clone"
+	HookAfterIP      = "after_ip"
+	HookBeforeRunner = "before_runner"
+	HookAfterJob     = "after_job"
+	HookOnFailure    = "on_failure"
+)
+
+// Run outcomes passed to hook executables as RVMM_OUTCOME
+const (
+	OutcomePending = "pending"
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeTimeout = "timeout"
+)
+
+// runOutcome returns the outcome of a run that ended with err
+func runOutcome(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return OutcomeTimeout
+	}
+	return OutcomeFailure
+}
+
+// HookEvent describes the run state exposed to hook executables
+type HookEvent struct {
+	InstanceName string
+	// Name the runner registered with GitHub under
+	RunnerName string
+	IP         string
+	SlotID     int
+	Outcome    string
+	Error      error
+	// Path of the failure diagnostics bundle, when one was collected
+	DiagnosticsBundle string
+}
+
+// HookRunner executes the host-side hooks configured in options.hooks
+type HookRunner struct {
+	cfg *config.Config
+	log *zap.Logger
+}
+
+// NewHookRunner creates a new hook runner
+func NewHookRunner(cfg *config.Config, log *zap.Logger) *HookRunner {
+	return &HookRunner{
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// Run executes every hook registered for the given stage in order.
+// It stops at the first failing hook and returns its error.
+func (h *HookRunner) Run(ctx context.Context, stage string, event HookEvent) error {
+	for i, hook := range h.hooksFor(stage) {
+		if err := h.runHook(ctx, stage, hook, event); err != nil {
+			return fmt.Errorf("%s hook %d (%s) failed: %w", stage, i, hook.Command, err)
+		}
+	}
+	return nil
+}
+
+func (h *HookRunner) hooksFor(stage string) []config.HookConfig {
+	hooks := h.cfg.Options.Hooks
+	switch stage {
+	case HookAfterClone:
+		return hooks.AfterClone
+	case HookAfterIP:
+		return hooks.AfterIP
+	case HookBeforeRunner:
+		return hooks.BeforeRunner
+	case HookAfterJob:
+		return hooks.AfterJob
+	case HookOnFailure:
+		return hooks.OnFailure
+	default:
+		return nil
+	}
+}
+
+func (h *HookRunner) runHook(ctx context.Context, stage string, hook config.HookConfig, event HookEvent) error {
+	timeout := hook.TimeoutDuration()
+	h.log.Info("Running hook",
+		zap.String("stage", stage),
+		zap.String("command", hook.Command),
+		zap.Duration("timeout", timeout),
+	)
+
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(hookCtx, hook.Command, hook.Args...)
+	cmd.Env = append(os.Environ(), hookEnv(stage, event)...)
+
+	output, err := cmd.CombinedOutput()
+	if hookCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s\nOutput: %s", timeout, string(output))
+	}
+	if err != nil {
+		return fmt.Errorf("%w\nOutput: %s", err, string(output))
+	}
+
+	h.log.Debug("Hook output", zap.String("stage", stage), zap.String("output", string(output)))
+	return nil
+}
+
+func hookEnv(stage string, event HookEvent) []string {
+	env := []string{
+		"RVMM_HOOK_STAGE=" + stage,
+		"RVMM_INSTANCE_NAME=" + event.InstanceName,
+		"RVMM_RUNNER_NAME=" + event.RunnerName,
+		"RVMM_VM_IP=" + event.IP,
+		"RVMM_SLOT_ID=" + strconv.Itoa(event.SlotID),
+		"RVMM_OUTCOME=" + event.Outcome,
+	}
+	if event.Error != nil {
+		env = append(env, "RVMM_ERROR="+event.Error.Error())
+	}
+	if event.DiagnosticsBundle != "" {
+		env = append(env, "RVMM_DIAGNOSTICS_BUNDLE="+event.DiagnosticsBundle)
+	}
+	return env
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunOutcome(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runnerErr := errors.New("runner execution failed: exit status 1")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{"clean exit", context.Background(), nil, OutcomeSuccess},
+		{"runner error", context.Background(), runnerErr, OutcomeFailure},
+		{"deadline", expired, runnerErr, OutcomeTimeout},
+		{"canceled", canceled, runnerErr, OutcomeFailure},
+	}
+	for _, tt := range tests {
+		if got := runOutcome(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: runOutcome() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
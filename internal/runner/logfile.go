@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogFilePath resolves options.log_file against the working directory. It
+// returns an empty string when file logging is disabled.
+func LogFilePath(cfg *config.Config) string {
+	path := cfg.Options.LogFile
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(cfg.Options.WorkingDirectory, path)
+}
+
+// logFile is the options.log_file sink. A reload can point it at another
+// file without rebuilding the loggers that write to it.
+type logFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// open switches the sink to path, or disables it when path is empty. The
+// current file is kept if path can't be opened.
+func (l *logFile) open(path string) error {
+	var file *os.File
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		var err error
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.path, l.file = path, file
+	return nil
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return len(p), nil
+	}
+	return l.file.Write(p)
+}
+
+func (l *logFile) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+// Close closes the current file
+func (l *logFile) Close() error {
+	return l.open("")
+}
+
+// tee returns log writing to the sink as well
+func (l *logFile) tee(log *zap.Logger) *zap.Logger {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), l, zap.InfoLevel)
+	return log.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	}))
+}
@@ -2,10 +2,13 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -16,11 +19,48 @@ import (
 
 // Run starts the main runner loop
 func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
+	return RunWithReload(ctx, log, cfg, nil)
+}
+
+// RunWithReload starts the main runner loop and reloads the config with load
+// on SIGHUP, or when the config file changes if options.watch_config is set.
+// A nil load disables reloading.
+func RunWithReload(ctx context.Context, log *zap.Logger, cfg *config.Config, load ConfigLoader) error {
 	// Check dependencies
 	if err := setup.CheckDependencies(); err != nil {
 		return err
 	}
 
+	// The working directory must be usable by this user on this machine
+	if err := cfg.ValidateHost(); err != nil {
+		return err
+	}
+
+	// Softnet isolation is provided by a separate helper binary
+	if cfg.VM.Network.Mode == config.NetworkSoftnet {
+		if _, err := exec.LookPath("softnet"); err != nil {
+			return fmt.Errorf("vm.network.mode is softnet but the softnet binary was not found (brew install cirruslabs/cli/softnet)")
+		}
+	}
+
+	// Make sure the configured VM sizes fit on this host
+	if cfg.VM.CPU > 0 || cfg.VM.MemoryMB > 0 {
+		cpus, memoryMB, err := setup.HostCapacity()
+		if err != nil {
+			log.Warn("Failed to read host capacity, skipping VM size check", zap.Error(err))
+		} else if err := cfg.ValidateCapacity(cpus, memoryMB); err != nil {
+			return err
+		}
+	}
+
+	// Also log to options.log_file; a reload can move it
+	logs := &logFile{}
+	if err := logs.open(LogFilePath(cfg)); err != nil {
+		return err
+	}
+	defer logs.Close()
+	log = logs.tee(log)
+
 	// Create context with signal handling
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -38,6 +78,24 @@ func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
 	// Create shared GitHub client (thread-safe)
 	github := NewGitHubClient(cfg, log)
 
+	// Runner names are unique per host and run; the VM instance name is not
+	// sent to GitHub
+	namer, err := NewRunnerNamer(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	// Claim the control socket first, so a second runner sharing the
+	// working directory fails before it touches any VM
+	var control *ControlServer
+	if ControlSocketPath(cfg) != "" {
+		control = NewControlServer(cfg, log)
+		if err := control.Listen(); err != nil {
+			return err
+		}
+		defer control.Close()
+	}
+
 	// Initialize image once before starting workers
 	var initOnce sync.Once
 	var initErr error
@@ -70,6 +128,10 @@ func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
 					initErr = fmt.Errorf("failed to pull image: %w", err)
 					return
 				}
+			} else if err := vm.EnsureDiskSize(ctx); err != nil {
+				// Skipped when the size was already applied to this image
+				initErr = fmt.Errorf("disk resize failed: %w", err)
+				return
 			}
 			log.Info("VM image initialized successfully")
 		})
@@ -81,17 +143,79 @@ func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
 		return initErr
 	}
 
-	// Create slot channel for bounded concurrency
-	slots := make(chan int, cfg.Options.MaxConcurrentRunners)
-	for i := 0; i < cfg.Options.MaxConcurrentRunners; i++ {
-		slots <- i
+	// Build or reuse the suspended snapshot; without one, runs cold boot
+	var snapshot *Snapshot
+	if cfg.VM.Snapshot.Enabled {
+		snapshot = NewSnapshot(cfg, log)
+		snapVM := NewVMManager(cfg, log)
+		if _, err := snapVM.ImageExists(ctx); err != nil {
+			log.Warn("Failed to resolve image for snapshot, using cold boot", zap.Error(err))
+		} else if err := snapshot.Ensure(ctx, snapVM); err != nil {
+			log.Warn("Failed to prepare snapshot, using cold boot", zap.Error(err))
+		}
+	}
+
+	// Slot pool for bounded concurrency, sized by the schedule and runtime
+	// overrides from the control socket
+	slots := NewSlotPool(0)
+	capacity := NewCapacity(cfg, log, slots)
+	go capacity.Run(ctx)
+
+	// Keep VMs booted ahead of demand, within the current capacity
+	var pool *WarmPool
+	var poolDone chan struct{}
+	if cfg.Options.WarmPool.Size > 0 {
+		pool = NewWarmPool(cfg, log, snapshot, slots)
+		poolDone = make(chan struct{})
+		go func() {
+			defer close(poolDone)
+			pool.Run(ctx)
+		}()
+	}
+	waitForPool := func() {
+		if poolDone != nil {
+			<-poolDone
+		}
+	}
+
+	// Workers read the live config when a run starts, so reloads apply to
+	// the next job without touching running ones
+	var live atomic.Pointer[config.Config]
+	live.Store(cfg)
+	if load != nil {
+		reload := newReloader(load, &live, capacity, logs, log)
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(hupCh)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-hupCh:
+					reload.reload("SIGHUP")
+				}
+			}
+		}()
+		if cfg.Options.WatchConfig && cfg.Source != "" {
+			go reload.watch(ctx)
+		}
+	}
+	if control != nil {
+		go func() {
+			if err := control.Serve(ctx, capacity); err != nil {
+				log.Error("Control socket stopped", zap.Error(err))
+			}
+		}()
 	}
 
 	// WaitGroup to track active workers
 	var wg sync.WaitGroup
 
+	status := capacity.Status()
 	log.Info("Starting runner loop",
-		zap.Int("max_concurrent_runners", cfg.Options.MaxConcurrentRunners),
+		zap.Int("max_concurrent_runners", status.MaxConcurrentRunners),
+		zap.String("schedule_window", status.ScheduleWindow),
 	)
 
 	// Main dispatch loop
@@ -100,48 +224,58 @@ func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
 		case <-ctx.Done():
 			log.Info("Context cancelled, waiting for active runners to complete")
 			wg.Wait()
+			waitForPool()
 			log.Info("All runners stopped")
 			return nil
 		default:
 		}
 
-		// Check shutdown flag
-		if cfg.Options.ShutdownFlagFile != "" {
-			if _, err := os.Stat(cfg.Options.ShutdownFlagFile); err == nil {
-				log.Info("Shutdown flag file detected, waiting for active runners")
-				wg.Wait()
-				return nil
-			}
+		// Acquire a slot (blocks if all slots are in use), watching for the
+		// shutdown flag meanwhile
+		slotID, err := waitForSlot(ctx, slots, shutdownFlagPollInterval, func() bool {
+			return shutdownRequested(live.Load())
+		})
+		if errors.Is(err, errShutdownRequested) {
+			log.Info("Shutdown flag file detected, waiting for active runners")
+			wg.Wait()
+			cancel()
+			waitForPool()
+			return nil
 		}
-
-		// Acquire a slot (blocks if all slots are in use)
-		var slotID int
-		select {
-		case <-ctx.Done():
+		if err != nil {
 			wg.Wait()
+			waitForPool()
 			return nil
-		case slotID = <-slots:
-			// Got a slot, continue
 		}
 
 		// Launch worker
 		wg.Add(1)
 		go func(slot int) {
 			defer wg.Done()
-			defer func() {
-				// Return slot to pool
-				slots <- slot
-			}()
+			// Return slot to pool; it is retired if the pool shrank meanwhile
+			defer slots.Release(slot)
+
+			// Config for this run; later reloads apply to the next one
+			cfg := live.Load()
 
 			// Create per-worker logger
 			workerLog := log.With(zap.Int("slot_id", slot))
 			workerLog.Info("Worker starting")
 
+			// Record a transcript of this run for the failure diagnostics bundle
+			var diag *DiagnosticsCollector
+			if cfg.Options.Diagnostics.Enabled {
+				diag, workerLog = NewDiagnosticsCollector(cfg, workerLog)
+			}
+
 			// Create per-worker VM manager to avoid race conditions
 			vm := NewVMManager(cfg, workerLog)
+			if snapshot != nil {
+				vm.UseSnapshot(snapshot)
+			}
 
 			// Run one iteration
-			if err := runOnce(ctx, workerLog, cfg, vm, github, slot); err != nil {
+			if err := runOnce(ctx, workerLog, cfg, vm, github, namer, diag, pool, slot); err != nil {
 				if ctx.Err() != nil {
 					// Context cancelled, exit gracefully
 					workerLog.Info("Worker stopped due to context cancellation")
@@ -157,7 +291,43 @@ func Run(ctx context.Context, log *zap.Logger, cfg *config.Config) error {
 	}
 }
 
-func runOnce(ctx context.Context, log *zap.Logger, cfg *config.Config, vm *VMManager, github *GitHubClient, slotID int) error {
+// shutdownFlagPollInterval is how often the shutdown flag is checked while
+// no slot is free, which may last for a whole schedule window
+const shutdownFlagPollInterval = 5 * time.Second
+
+// errShutdownRequested is returned by waitForSlot when the shutdown flag
+// file exists
+var errShutdownRequested = errors.New("shutdown flag file detected")
+
+// waitForSlot acquires a runner slot, checking shutdown before and every
+// interval while waiting
+func waitForSlot(ctx context.Context, slots *SlotPool, interval time.Duration, shutdown func() bool) (int, error) {
+	for {
+		if shutdown() {
+			return -1, errShutdownRequested
+		}
+		acquireCtx, cancel := context.WithTimeout(ctx, interval)
+		slot, err := slots.Acquire(acquireCtx)
+		cancel()
+		if err == nil {
+			return slot, nil
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+	}
+}
+
+// shutdownRequested reports whether options.shutdown_flag_file exists
+func shutdownRequested(cfg *config.Config) bool {
+	if cfg.Options.ShutdownFlagFile == "" {
+		return false
+	}
+	_, err := os.Stat(cfg.Options.ShutdownFlagFile)
+	return err == nil
+}
+
+func runOnce(ctx context.Context, log *zap.Logger, cfg *config.Config, vm *VMManager, github *GitHubClient, namer *RunnerNamer, diag *DiagnosticsCollector, pool *WarmPool, slotID int) (err error) {
 	log.Info("Starting new run")
 
 	// Get registration token
@@ -167,52 +337,119 @@ func runOnce(ctx context.Context, log *zap.Logger, cfg *config.Config, vm *VMMan
 	}
 
 	// Generate instance name using slot ID
-	instanceName := fmt.Sprintf("%s_%d", cfg.GitHub.RunnerName, slotID)
+	instanceName := InstanceName(cfg, slotID)
 
-	// Ensure cleanup happens
-	defer vm.Cleanup(ctx, instanceName)
+	runnerName, err := namer.Name(slotID)
+	if err != nil {
+		return err
+	}
 
-	// Clone VM
-	if err := vm.Clone(ctx, instanceName); err != nil {
-		return fmt.Errorf("failed to clone VM: %w", err)
+	// Take a pre-booted VM from the warm pool when one is ready
+	var warm *WarmVM
+	if pool != nil {
+		if warm = pool.Acquire(); warm != nil {
+			instanceName = warm.Name
+		}
 	}
 
-	// Start VM
-	vmCmd, err := vm.Start(ctx, instanceName)
-	if err != nil {
-		return fmt.Errorf("failed to start VM: %w", err)
+	hooks := NewHookRunner(cfg, log)
+	ssh := NewSSHClient(cfg, log)
+	event := HookEvent{
+		InstanceName: instanceName,
+		RunnerName:   runnerName,
+		SlotID:       slotID,
+		Outcome:      OutcomePending,
 	}
 
-	// Wait for the VM process in the background
-	vmDone := make(chan error, 1)
-	go func() {
-		vmDone <- vmCmd.Wait()
+	// Ensure cleanup happens
+	defer vm.Cleanup(ctx, instanceName)
+
+	// Collect diagnostics and run failure hooks before the VM is cleaned up
+	// so they can still reach it
+	defer func() {
+		if err == nil {
+			return
+		}
+		event.Outcome = runOutcome(ctx, err)
+		event.Error = err
+		if diag != nil && ctx.Err() == nil {
+			bundle, diagErr := diag.Collect(ctx, ssh, instanceName, event.IP, err)
+			if diagErr != nil {
+				log.Warn("Failed to collect failure diagnostics", zap.Error(diagErr))
+			}
+			event.DiagnosticsBundle = bundle
+		}
+		if hookErr := hooks.Run(context.WithoutCancel(ctx), HookOnFailure, event); hookErr != nil {
+			log.Warn("Failure hook failed", zap.Error(hookErr))
+		}
 	}()
 
-	// Wait for IP
-	ip, err := vm.WaitForIP(ctx, instanceName)
-	if err != nil {
-		return fmt.Errorf("failed to get VM IP: %w", err)
+	// Clone and boot the VM, unless a warm one was handed to us
+	var vmDone <-chan error
+	var ip string
+	if warm != nil {
+		vmDone, ip = warm.Done, warm.IP
+		if err := hooks.Run(ctx, HookAfterClone, event); err != nil {
+			return err
+		}
+	} else {
+		vmDone, ip, err = bootInstance(ctx, log, vm, hooks, &event, slotID)
+		if err != nil {
+			return err
+		}
 	}
+	event.IP = ip
 
-	// Create SSH client
-	ssh := NewSSHClient(cfg, log)
+	if err := hooks.Run(ctx, HookAfterIP, event); err != nil {
+		return err
+	}
 
 	// Wait for SSH
 	if err := ssh.WaitForSSH(ctx, ip); err != nil {
 		return fmt.Errorf("SSH not available: %w", err)
 	}
 
+	// Make sure the guest is actually usable before touching it
+	if err := ssh.WaitForReady(ctx, ip); err != nil {
+		return fmt.Errorf("VM not ready: %w", err)
+	}
+
+	// Prepare the guest before the runner is registered
+	if err := ssh.RunBootstrap(ctx, ip); err != nil {
+		return fmt.Errorf("guest bootstrap failed: %w", err)
+	}
+
 	// Configure runner
-	if err := ssh.ConfigureRunner(ctx, ip, token, instanceName); err != nil {
+	if err := ssh.ConfigureRunner(ctx, ip, token, runnerName); err != nil {
 		return fmt.Errorf("failed to configure runner: %w", err)
 	}
 
-	// Run the runner (blocks until job completes or runner exits)
+	if err := hooks.Run(ctx, HookBeforeRunner, event); err != nil {
+		return err
+	}
+
+	// Run the runner (blocks until the job completes, the runner exits or
+	// options.job_timeout passes)
 	log.Info("Runner started, waiting for job")
-	if err := ssh.RunRunner(ctx, ip); err != nil {
-		// Runner exit is expected after job completion
-		log.Info("Runner exited", zap.Error(err))
+	var runErr error
+	event.Outcome, runErr = runJob(ctx, cfg, func(ctx context.Context) error {
+		return ssh.RunRunner(ctx, ip)
+	})
+	if runErr != nil {
+		log.Warn("Runner exited with an error", zap.Error(runErr), zap.String("outcome", event.Outcome))
+		event.Error = runErr
+		if diag != nil && ctx.Err() == nil {
+			bundle, diagErr := diag.Collect(ctx, ssh, instanceName, ip, runErr)
+			if diagErr != nil {
+				log.Warn("Failed to collect runner diagnostics", zap.Error(diagErr))
+			}
+			event.DiagnosticsBundle = bundle
+		}
+	}
+
+	// Post-job hooks are informational and never fail the run
+	if err := hooks.Run(ctx, HookAfterJob, event); err != nil {
+		log.Warn("After-job hook failed", zap.Error(err))
 	}
 
 	// Stop VM
@@ -228,6 +465,94 @@ func runOnce(ctx context.Context, log *zap.Logger, cfg *config.Config, vm *VMMan
 		log.Warn("VM process did not exit in time")
 	}
 
-	log.Info("Run completed successfully")
+	log.Info("Run completed", zap.String("outcome", event.Outcome))
 	return nil
 }
+
+// runJob runs the runner under options.job_timeout and returns the outcome
+func runJob(ctx context.Context, cfg *config.Config, run func(context.Context) error) (string, error) {
+	timeout := cfg.Options.JobTimeoutDuration()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := run(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("job exceeded options.job_timeout (%s): %w", timeout, err)
+	}
+	return runOutcome(ctx, err), err
+}
+
+// bootInstance clones and starts the VM for a run and waits for its IP. When a
+// suspended snapshot is ready the clone is resumed from it, falling back to a
+// cold boot if the resume fails. The after_clone hooks run once per
+// instance, even when the fallback clones it again.
+// hooks may be nil when the VM is booted ahead of a run (warm pool).
+func bootInstance(ctx context.Context, log *zap.Logger, vm *VMManager, hooks *HookRunner, event *HookEvent, slotID int) (<-chan error, string, error) {
+	instanceName := event.InstanceName
+	vmDone := make(chan error, 1)
+	cloneHooksRan := false
+
+	if vm.CanResume() {
+		vmCmd, err := vm.Resume(ctx, instanceName)
+		if err == nil {
+			if hooks != nil {
+				cloneHooksRan = true
+				if err := hooks.Run(ctx, HookAfterClone, *event); err != nil {
+					return nil, "", err
+				}
+			}
+			go func() {
+				vmDone <- vmCmd.Wait()
+			}()
+
+			var ip string
+			ip, err = vm.waitForIPWithin(ctx, instanceName, vm.cfg.VM.Snapshot.ResumeTimeoutDuration())
+			if err == nil {
+				return vmDone, ip, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		log.Warn("Resume from snapshot failed, falling back to cold boot", zap.Error(err))
+		vm.Cleanup(ctx, instanceName)
+		vmDone = make(chan error, 1)
+	}
+
+	// Clone VM
+	if err := vm.Clone(ctx, instanceName); err != nil {
+		return nil, "", fmt.Errorf("failed to clone VM: %w", err)
+	}
+
+	// Apply per-host CPU, memory and disk sizing
+	if err := vm.ApplyResources(ctx, instanceName); err != nil {
+		return nil, "", fmt.Errorf("failed to size VM: %w", err)
+	}
+
+	if hooks != nil && !cloneHooksRan {
+		if err := hooks.Run(ctx, HookAfterClone, *event); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Start VM
+	vmCmd, err := vm.Start(ctx, instanceName, slotID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to start VM: %w", err)
+	}
+
+	// Wait for the VM process in the background
+	go func() {
+		vmDone <- vmCmd.Wait()
+	}()
+
+	// Wait for IP
+	ip, err := vm.WaitForIP(ctx, instanceName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get VM IP: %w", err)
+	}
+
+	return vmDone, ip, nil
+}
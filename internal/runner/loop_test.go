@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// fakeCommand puts a shell script called name first on PATH for the test
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunJob(t *testing.T) {
+	runnerErr := errors.New("runner execution failed: exit status 1")
+	// blocking stands in for a runner that never finishes its job
+	blocking := func(ctx context.Context) error {
+		<-ctx.Done()
+		return runnerErr
+	}
+
+	tests := []struct {
+		name    string
+		timeout string
+		run     func(context.Context) error
+		want    string
+		wantErr string
+	}{
+		{"clean exit", "", func(context.Context) error { return nil }, OutcomeSuccess, ""},
+		{"runner error", "1h", func(context.Context) error { return runnerErr }, OutcomeFailure, "exit status 1"},
+		{"job timeout", "20ms", blocking, OutcomeTimeout, "options.job_timeout (20ms)"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.Options.JobTimeout = tt.timeout
+		outcome, err := runJob(context.Background(), cfg, tt.run)
+		if outcome != tt.want {
+			t.Errorf("%s: outcome = %q, want %q", tt.name, outcome, tt.want)
+		}
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunJobDeadline(t *testing.T) {
+	cfg := testConfig(t)
+	run := func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("runner context has a deadline without options.job_timeout")
+		}
+		return nil
+	}
+	if _, err := runJob(context.Background(), cfg, run); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutting down is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg.Options.JobTimeout = "1h"
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	outcome, _ := runJob(ctx, cfg, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if outcome != OutcomeFailure {
+		t.Errorf("outcome after shutdown = %q, want %q", outcome, OutcomeFailure)
+	}
+}
+
+func TestBootInstanceResumeFallbackRunsCloneHooksOnce(t *testing.T) {
+	// Resuming the snapshot never yields an IP; the cold boot does
+	state := t.TempDir()
+	t.Setenv("STATE", state)
+	fakeCommand(t, "tart", `case "$1" in
+ip) [ -f "$STATE/booted" ] && echo 192.168.64.2 && exit 0; exit 1 ;;
+run) case "$*" in *--suspendable*) exit 1 ;; esac; touch "$STATE/booted"; sleep 1 ;;
+esac
+exit 0
+`)
+	hookLog := filepath.Join(state, "hooks")
+	hook := filepath.Join(state, "hook.sh")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho \"$RVMM_HOOK_STAGE $RVMM_INSTANCE_NAME\" >> "+hookLog+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t)
+	cfg.VM.Display = ""
+	cfg.VM.Snapshot.ResumeTimeout = "100ms"
+	cfg.VM.Boot.IPPollInterval = "10ms"
+	cfg.Options.Hooks.AfterClone = []config.HookConfig{{Command: hook}}
+
+	snapshot := NewSnapshot(cfg, zap.NewNop())
+	snapshot.setReady(true, "test")
+	vm := NewVMManager(cfg, zap.NewNop())
+	vm.UseSnapshot(snapshot)
+
+	event := HookEvent{InstanceName: "runner_0", SlotID: 0, Outcome: OutcomePending}
+	_, ip, err := bootInstance(context.Background(), zap.NewNop(), vm, NewHookRunner(cfg, zap.NewNop()), &event, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "192.168.64.2" {
+		t.Errorf("ip = %q, want the cold-booted VM's", ip)
+	}
+	if got := readTestFile(t, hookLog); got != "after_clone runner_0\n" {
+		t.Errorf("after_clone hook ran:\n%s\nwant once", got)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// hostIDFile persists the derived host ID in the working directory
+const hostIDFile = "host-id"
+
+var runnerNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RunnerNameData is the data available to github.runner_name_template
+type RunnerNameData struct {
+	// Pool is github.runner_name
+	Pool   string
+	HostID string
+	Slot   int
+	// Suffix is a short random string, different for every run
+	Suffix string
+}
+
+// RunnerNamer generates GitHub runner names that are unique across hosts
+type RunnerNamer struct {
+	tmpl   *template.Template
+	pool   string
+	hostID string
+}
+
+// NewRunnerNamer resolves the host ID and parses the name template
+func NewRunnerNamer(cfg *config.Config, log *zap.Logger) (*RunnerNamer, error) {
+	text := cfg.GitHub.RunnerNameTemplate
+	if text == "" {
+		text = config.DefaultRunnerNameTemplate
+	}
+	tmpl, err := template.New("runner_name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github.runner_name_template: %w", err)
+	}
+
+	hostID, err := ResolveHostID(cfg)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Resolved host ID", zap.String("host_id", hostID))
+
+	return &RunnerNamer{
+		tmpl:   tmpl,
+		pool:   cfg.GitHub.RunnerName,
+		hostID: hostID,
+	}, nil
+}
+
+// Name renders the GitHub runner name for a run in the given slot
+func (n *RunnerNamer) Name(slot int) (string, error) {
+	suffix, err := randomHex(3)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := RunnerNameData{Pool: n.pool, HostID: n.hostID, Slot: slot, Suffix: suffix}
+	if err := n.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render runner name: %w", err)
+	}
+
+	name := sanitizeRunnerName(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("github.runner_name_template rendered an empty name")
+	}
+	return name, nil
+}
+
+// InstanceName returns the local Tart VM name for a slot. It stays
+// <runner_name>_<slot> whatever name the runner registers with GitHub.
+func InstanceName(cfg *config.Config, slot int) string {
+	return fmt.Sprintf("%s_%d", cfg.GitHub.RunnerName, slot)
+}
+
+// ResolveHostID returns options.host_id if set, otherwise the ID persisted in
+// the working directory, deriving and saving one from the hostname on first use
+func ResolveHostID(cfg *config.Config) (string, error) {
+	if cfg.Options.HostID != "" {
+		return sanitizeRunnerName(cfg.Options.HostID), nil
+	}
+
+	path := filepath.Join(cfg.Options.WorkingDirectory, hostIDFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+
+	id, err := deriveHostID()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(cfg.Options.WorkingDirectory, 0755); err != nil {
+		return "", fmt.Errorf("failed to create working directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to persist host ID: %w", err)
+	}
+	return id, nil
+}
+
+// deriveHostID builds an ID from the short hostname plus a random tag, so
+// cloned machines with the same hostname still get distinct IDs
+func deriveHostID() (string, error) {
+	tag, err := randomHex(2)
+	if err != nil {
+		return "", err
+	}
+	hostname, _ := os.Hostname()
+	hostname, _, _ = strings.Cut(hostname, ".")
+	hostname = strings.ToLower(sanitizeRunnerName(hostname))
+	if hostname == "" {
+		return tag, nil
+	}
+	return hostname + "-" + tag, nil
+}
+
+func sanitizeRunnerName(name string) string {
+	return strings.Trim(runnerNameInvalidChars.ReplaceAllString(name, "-"), "-")
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random suffix: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
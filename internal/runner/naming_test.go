@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRunnerNamerName(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", `^runner-host-1-0-[0-9a-f]{6}$`},
+		{"{{.Pool}}-{{.HostID}}-{{.Slot}}", `^runner-host-1-0$`},
+		{"ci {{.HostID}}/{{.Slot}}", `^ci-host-1-0$`},
+		{"--{{.Pool}}--", `^runner$`},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.GitHub.RunnerName = "runner"
+		cfg.GitHub.RunnerNameTemplate = tt.template
+		cfg.Options.HostID = "host-1"
+		namer, err := NewRunnerNamer(cfg, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewRunnerNamer(%q): %v", tt.template, err)
+		}
+		got, err := namer.Name(0)
+		if err != nil {
+			t.Fatalf("Name with %q: %v", tt.template, err)
+		}
+		if !regexp.MustCompile(tt.want).MatchString(got) {
+			t.Errorf("Name with %q = %q, want match for %s", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerNamerSuffixDiffers(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Options.HostID = "host-1"
+	namer, err := NewRunnerNamer(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := namer.Name(0)
+	second, _ := namer.Name(0)
+	if first == second {
+		t.Errorf("two runs in the same slot got the same name %q", first)
+	}
+}
+
+func TestRunnerNamerErrors(t *testing.T) {
+	for _, template := range []string{"{{.Pool", "{{.Missing}}", "{{if false}}x{{end}}"} {
+		cfg := testConfig(t)
+		cfg.GitHub.RunnerNameTemplate = template
+		cfg.Options.HostID = "host-1"
+		namer, err := NewRunnerNamer(cfg, zap.NewNop())
+		if err == nil {
+			_, err = namer.Name(0)
+		}
+		if err == nil {
+			t.Errorf("template %q: expected an error", template)
+		}
+	}
+}
+
+func TestResolveHostIDPersists(t *testing.T) {
+	cfg := testConfig(t)
+	first, err := ResolveHostID(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "" || strings.Trim(first, "-") != first {
+		t.Errorf("derived host ID %q is not a valid name", first)
+	}
+	if got := strings.TrimSpace(readTestFile(t, filepath.Join(cfg.Options.WorkingDirectory, hostIDFile))); got != first {
+		t.Errorf("persisted host ID = %q, want %q", got, first)
+	}
+
+	// Later starts reuse the saved ID instead of deriving a new one
+	second, err := ResolveHostID(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Errorf("host ID changed from %q to %q", first, second)
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.Options.WorkingDirectory, hostIDFile), []byte("mini-07\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ResolveHostID(cfg); got != "mini-07" {
+		t.Errorf("host ID from file = %q, want mini-07", got)
+	}
+}
+
+func TestResolveHostIDFromConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Options.HostID = "Mac Mini #3"
+	got, err := ResolveHostID(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Mac-Mini-3" {
+		t.Errorf("ResolveHostID = %q, want Mac-Mini-3", got)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Options.WorkingDirectory, hostIDFile)); !os.IsNotExist(err) {
+		t.Errorf("options.host_id should not be persisted, stat error = %v", err)
+	}
+}
+
+func TestInstanceNameIgnoresTemplate(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.GitHub.RunnerName = "runner"
+	cfg.GitHub.RunnerNameTemplate = "{{.HostID}}-{{.Suffix}}"
+	cfg.Options.HostID = "host-1"
+	namer, err := NewRunnerNamer(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runnerName, err := namer.Name(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := InstanceName(cfg, 1); got != "runner_1" {
+		t.Errorf("InstanceName = %q, want runner_1", got)
+	}
+	if !strings.HasPrefix(runnerName, "host-1-") {
+		t.Errorf("runner name = %q, want the template to apply", runnerName)
+	}
+}
@@ -0,0 +1,323 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	pullPercentRegex = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)%`)
+	pullSizeRegex    = regexp.MustCompile(`pulling (\w+) \(([\d.]+) ?([KMGT]?B)`)
+	pullPhaseRegex   = regexp.MustCompile(`^pulling (\w+)`)
+	pullLayersRegex  = regexp.MustCompile(`(\d+)/(\d+) layers?`)
+)
+
+// permanentPullErrorRegex matches output showing a pull will not succeed by
+// retrying: tart's own authentication failure, or an OCI error code the
+// registry returned for bad credentials or a repository that doesn't exist
+// (tart prints the response body, possibly with escaped quotes). Plain words
+// such as "not found" or "denied" also show up in transient proxy and token
+// endpoint errors, and a freshly pushed tag can be MANIFEST_UNKNOWN for a
+// moment, so those are retried.
+var permanentPullErrorRegex = regexp.MustCompile(
+	`AuthFailed|"code\\?"\s*:\s*\\?"(?:UNAUTHORIZED|DENIED|NAME_UNKNOWN|NAME_INVALID)\b`)
+
+// PullProgress is a structured snapshot of an in-flight `tart pull`
+type PullProgress struct {
+	Ref         string
+	Attempt     int
+	Phase       string
+	Percent     float64
+	TotalBytes  int64
+	DoneBytes   int64
+	LayersDone  int
+	LayersTotal int
+	ETA         time.Duration
+}
+
+// String formats the progress for log lines and the TUI
+func (p PullProgress) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pull %s: %s %.0f%%", p.Ref, p.Phase, p.Percent)
+	if p.TotalBytes > 0 {
+		fmt.Fprintf(&b, " (%s / %s)", formatBytes(p.DoneBytes), formatBytes(p.TotalBytes))
+	}
+	if p.LayersTotal > 0 {
+		fmt.Fprintf(&b, " layers %d/%d", p.LayersDone, p.LayersTotal)
+	}
+	if p.ETA > 0 {
+		fmt.Fprintf(&b, " ETA %s", p.ETA.Round(time.Second))
+	}
+	if p.Attempt > 1 {
+		fmt.Fprintf(&b, " [attempt %d]", p.Attempt)
+	}
+	return b.String()
+}
+
+// PullRef pulls an image with `tart pull`, reporting progress and retrying
+// transient failures. Tart keeps already-downloaded layers in its cache, so
+// retries resume rather than starting over. onProgress may be nil.
+func (v *VMManager) PullRef(ctx context.Context, ref string, onProgress func(PullProgress)) error {
+	attempts := v.cfg.Registry.PullRetries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := v.cfg.Registry.PullRetryDelayDuration()
+
+	var lastErr error
+	var lastOutput string
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			v.log.Warn("Retrying image pull",
+				zap.String("ref", ref),
+				zap.Int("attempt", attempt),
+				zap.Int("max_attempts", attempts),
+				zap.Duration("delay", delay),
+				zap.Error(lastErr),
+			)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		output, err := v.pullOnce(ctx, ref, attempt, onProgress)
+		if err == nil {
+			v.log.Info("Image pulled", zap.String("ref", ref), zap.Int("attempt", attempt))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		lastErr, lastOutput = err, output
+		if isPermanentPullError(output) {
+			return fmt.Errorf("tart pull failed: %w\nOutput: %s", err, output)
+		}
+	}
+
+	return fmt.Errorf("tart pull failed after %d attempts: %w\nOutput: %s", attempts, lastErr, lastOutput)
+}
+
+// pullOnce runs a single `tart pull` and returns its non-progress output
+func (v *VMManager) pullOnce(ctx context.Context, ref string, attempt int, onProgress func(PullProgress)) (string, error) {
+	cmd := exec.CommandContext(ctx, "tart", "pull", ref, "--concurrency", "1")
+	if v.cfg.Registry.Username != "" {
+		cmd.Env = append(os.Environ(),
+			"TART_REGISTRY_USERNAME="+v.cfg.Registry.Username,
+			"TART_REGISTRY_PASSWORD="+v.cfg.Registry.Password,
+		)
+	}
+	// Interrupt instead of kill on cancellation so tart can flush partial layers
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGINT)
+	}
+	cmd.WaitDelay = 15 * time.Second
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start tart pull: %w", err)
+	}
+
+	tracker := newPullTracker(ref, attempt)
+	var output bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(pr)
+		scanner.Split(scanLinesOrCR)
+		lastReported := time.Time{}
+		lastPercent := -1.0
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			progress, isProgress := tracker.update(line)
+			if !isProgress {
+				output.WriteString(line + "\n")
+				v.log.Info("tart pull", zap.String("output", line))
+				continue
+			}
+			// Throttle progress reports to every whole percent or 10 seconds
+			if progress.Percent-lastPercent < 1 && time.Since(lastReported) < 10*time.Second {
+				continue
+			}
+			lastPercent = progress.Percent
+			lastReported = time.Now()
+			v.log.Info("Image pull progress",
+				zap.String("ref", ref),
+				zap.String("phase", progress.Phase),
+				zap.Float64("percent", progress.Percent),
+				zap.Int64("done_bytes", progress.DoneBytes),
+				zap.Int64("total_bytes", progress.TotalBytes),
+				zap.Int("layers_done", progress.LayersDone),
+				zap.Int("layers_total", progress.LayersTotal),
+				zap.Duration("eta", progress.ETA),
+			)
+			if onProgress != nil {
+				onProgress(progress)
+			}
+		}
+		// Drain in case the scanner stopped early so tart doesn't block
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Wait()
+	pw.Close()
+	wg.Wait()
+
+	return output.String(), err
+}
+
+// pullTracker derives structured progress from tart's human-readable output
+type pullTracker struct {
+	progress     PullProgress
+	phaseStarted time.Time
+}
+
+func newPullTracker(ref string, attempt int) *pullTracker {
+	return &pullTracker{
+		progress:     PullProgress{Ref: ref, Attempt: attempt, Phase: "starting"},
+		phaseStarted: time.Now(),
+	}
+}
+
+// update folds a line of tart output into the progress and reports whether
+// the line was a progress line
+func (t *pullTracker) update(line string) (PullProgress, bool) {
+	isProgress := false
+
+	if m := pullPhaseRegex.FindStringSubmatch(line); m != nil && m[1] != t.progress.Phase {
+		t.progress.Phase = m[1]
+		t.progress.Percent = 0
+		t.progress.DoneBytes = 0
+		t.progress.TotalBytes = 0
+		t.progress.ETA = 0
+		t.phaseStarted = time.Now()
+	}
+	if m := pullSizeRegex.FindStringSubmatch(line); m != nil {
+		t.progress.TotalBytes = parseSize(m[2], m[3])
+	}
+	if m := pullLayersRegex.FindStringSubmatch(line); m != nil {
+		t.progress.LayersDone, _ = strconv.Atoi(m[1])
+		t.progress.LayersTotal, _ = strconv.Atoi(m[2])
+		isProgress = true
+	}
+	if m := pullPercentRegex.FindStringSubmatch(line); m != nil {
+		if pct, err := strconv.ParseFloat(m[1], 64); err == nil && pct <= 100 {
+			t.progress.Percent = pct
+			isProgress = true
+		}
+	}
+
+	if t.progress.TotalBytes > 0 {
+		t.progress.DoneBytes = int64(float64(t.progress.TotalBytes) * t.progress.Percent / 100)
+	}
+	if t.progress.Percent > 0 && t.progress.Percent < 100 {
+		elapsed := time.Since(t.phaseStarted)
+		t.progress.ETA = time.Duration(float64(elapsed) * (100 - t.progress.Percent) / t.progress.Percent)
+	} else {
+		t.progress.ETA = 0
+	}
+
+	return t.progress, isProgress
+}
+
+// pruneOtherCachedTags removes cached OCI images of the same repository with
+// a different tag, keeping the target's cache (and any partial layers) intact.
+// It only prunes for an explicit tag, and only touches tag symlinks and
+// digest directories, so nested repositories are never removed.
+func (v *VMManager) pruneOtherCachedTags() {
+	repo, tag, _ := splitImageRef(v.GetRegistryPath())
+	if tag == "" {
+		return
+	}
+	repoDir := filepath.Join(ociCacheDir(), repo)
+	// Tags are symlinks to digest directories in the same repository folder
+	keep := map[string]bool{tag: true}
+	if target, err := filepath.EvalSymlinks(filepath.Join(repoDir, tag)); err == nil {
+		keep[filepath.Base(target)] = true
+	}
+	entries, err := os.ReadDir(repoDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(repoDir, entry.Name())
+		if keep[entry.Name()] {
+			continue
+		}
+		if entry.Type()&os.ModeSymlink == 0 && !strings.HasPrefix(entry.Name(), "sha256:") {
+			continue
+		}
+		v.log.Info("Removing old cached image", zap.String("path", path))
+		if err := os.RemoveAll(path); err != nil {
+			v.log.Warn("Failed to remove old cached image", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
+
+func isPermanentPullError(output string) bool {
+	return permanentPullErrorRegex.MatchString(output)
+}
+
+// scanLinesOrCR splits on \n or \r so in-place progress updates become lines
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func parseSize(value, unit string) int64 {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	multipliers := map[string]float64{
+		"B":  1,
+		"KB": 1e3,
+		"MB": 1e6,
+		"GB": 1e9,
+		"TB": 1e12,
+	}
+	return int64(n * multipliers[unit])
+}
+
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGT"[exp])
+}
@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestIsPermanentPullError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{`Error: AuthFailed(why: "received unexpected HTTP status code 401 while retrieving an authentication token", details: "")`, true},
+		{`Error: UnexpectedHTTPStatusCode(when: "pulling manifest", code: 401, details: "{\"errors\":[{\"code\":\"UNAUTHORIZED\",\"message\":\"authentication required\"}]}")`, true},
+		{`details: "{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}"`, true},
+		{`details: "{"errors":[{"code":"NAME_UNKNOWN","message":"repository name not known to registry"}]}"`, true},
+		{`details: "{"errors":[{"code":"NAME_INVALID"}]}"`, true},
+		// Transient: retried
+		{`details: "{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}"`, false},
+		{`Error: UnexpectedHTTPStatusCode(when: "pulling manifest", code: 404, details: "404 page not found")`, false},
+		{`Error: UnexpectedHTTPStatusCode(when: "retrieving token", code: 503, details: "Service Unavailable")`, false},
+		{"proxy: access denied by upstream, try again later", false},
+		{"The network connection was lost.", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isPermanentPullError(tt.output); got != tt.want {
+			t.Errorf("isPermanentPullError(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestPullTracker(t *testing.T) {
+	tracker := newPullTracker("ghcr.io/acme/runner:latest", 2)
+	if _, ok := tracker.update("pulling manifest..."); ok {
+		t.Error("phase line reported as progress")
+	}
+	tracker.update("pulling disk (25.0 GB compressed)...")
+	progress, ok := tracker.update("40%")
+	if !ok {
+		t.Fatal("percent line not reported as progress")
+	}
+	if progress.Phase != "disk" || progress.Percent != 40 || progress.TotalBytes != 25e9 || progress.DoneBytes != 10e9 {
+		t.Errorf("progress = %+v", progress)
+	}
+	if progress.Attempt != 2 {
+		t.Errorf("Attempt = %d, want 2", progress.Attempt)
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	tests := []struct {
+		ref, repo, tag, digest string
+	}{
+		{"ghcr.io/org/macos:latest", "ghcr.io/org/macos", "latest", ""},
+		{"ghcr.io/org/macos", "ghcr.io/org/macos", "", ""},
+		{"registry.local:5000/macos", "registry.local:5000/macos", "", ""},
+		{"registry.local:5000/macos:15", "registry.local:5000/macos", "15", ""},
+		{"ghcr.io/org/macos@sha256:abc", "ghcr.io/org/macos", "", "sha256:abc"},
+		{"runner", "runner", "", ""},
+	}
+	for _, tt := range tests {
+		repo, tag, digest := splitImageRef(tt.ref)
+		if repo != tt.repo || tag != tt.tag || digest != tt.digest {
+			t.Errorf("splitImageRef(%q) = %q, %q, %q; want %q, %q, %q", tt.ref, repo, tag, digest, tt.repo, tt.tag, tt.digest)
+		}
+	}
+}
+
+func TestGetCachePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tests := map[string]string{
+		"ghcr.io/org/macos:15":            "ghcr.io/org/macos/15",
+		"ghcr.io/org/macos":               "ghcr.io/org/macos/latest",
+		"registry.local:5000/macos:15":    "registry.local:5000/macos/15",
+		"ghcr.io/org/macos@sha256:abc123": "ghcr.io/org/macos/sha256:abc123",
+	}
+	for image, want := range tests {
+		cfg := testConfig(t)
+		cfg.Registry.ImageName = image
+		got := NewVMManager(cfg, zap.NewNop()).GetCachePath()
+		if want = filepath.Join(home, ".tart", "cache", "OCIs", want); got != want {
+			t.Errorf("GetCachePath() for %q = %q, want %q", image, got, want)
+		}
+	}
+}
+
+// newTestOCICache lays out a Tart OCI cache with two repositories under
+// ghcr.io/org, one nested in the other, and returns its root
+func newTestOCICache(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := filepath.Join(home, ".tart", "cache", "OCIs", "ghcr.io", "org")
+	for _, dir := range []string{"macos/sha256:new", "macos/sha256:old", "macos/xcode/sha256:x", "linux/sha256:l"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{"macos/15": "sha256:new", "macos/14": "sha256:old", "macos/xcode/16": "sha256:x", "linux/latest": "sha256:l"}
+	for link, target := range links {
+		if err := os.Symlink(filepath.Join(root, filepath.Dir(link), target), filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// listTestCache returns every path below root, relative to it
+func listTestCache(t *testing.T, root string) string {
+	t.Helper()
+	var paths []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if rel, _ := filepath.Rel(root, path); err == nil && rel != "." {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return strings.Join(paths, " ")
+}
+
+func TestPruneOtherCachedTags(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"ghcr.io/org/macos:15", "linux linux/latest linux/sha256:l macos macos/15 macos/sha256:new macos/xcode macos/xcode/16 macos/xcode/sha256:x"},
+		// Without an explicit tag nothing is pruned
+		{"ghcr.io/org/macos", "linux linux/latest linux/sha256:l macos macos/14 macos/15 macos/sha256:new macos/sha256:old macos/xcode macos/xcode/16 macos/xcode/sha256:x"},
+		{"ghcr.io/org/macos@sha256:new", "linux linux/latest linux/sha256:l macos macos/14 macos/15 macos/sha256:new macos/sha256:old macos/xcode macos/xcode/16 macos/xcode/sha256:x"},
+	}
+	for _, tt := range tests {
+		root := newTestOCICache(t)
+		cfg := testConfig(t)
+		cfg.Registry.ImageName = tt.image
+		NewVMManager(cfg, zap.NewNop()).pruneOtherCachedTags()
+		if got := listTestCache(t, root); got != tt.want {
+			t.Errorf("after pruning for %q:\n got %s\nwant %s", tt.image, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// WaitForReady runs the configured readiness probes until all of them pass
+// or vm.boot.probe_timeout elapses. Probes that already passed are not re-run,
+// and a probe still running when the budget runs out is stopped.
+func (s *SSHClient) WaitForReady(ctx context.Context, ip string) error {
+	probes := s.cfg.VM.Readiness
+	if len(probes) == 0 {
+		return nil
+	}
+
+	timeoutAfter := s.cfg.VM.Boot.ProbeTimeoutDuration()
+	s.log.Info("Running readiness probes",
+		zap.Int("probes", len(probes)),
+		zap.Duration("timeout", timeoutAfter),
+	)
+
+	ticker := time.NewTicker(s.cfg.VM.Boot.ProbePollIntervalDuration())
+	defer ticker.Stop()
+
+	budget, cancel := context.WithTimeout(ctx, timeoutAfter)
+	defer cancel()
+	passed := make([]bool, len(probes))
+	var lastErr error
+
+	for {
+		lastErr = nil
+		for i, probe := range probes {
+			if passed[i] {
+				continue
+			}
+			if err := s.runProbe(budget, ip, probe); err != nil {
+				lastErr = fmt.Errorf("probe %s: %w", probeName(probe), err)
+				continue
+			}
+			passed[i] = true
+			s.log.Info("Readiness probe passed", zap.String("probe", probeName(probe)))
+		}
+		if lastErr == nil {
+			s.log.Info("VM is ready")
+			return nil
+		}
+
+		select {
+		case <-budget.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("timeout waiting for readiness after %s: %w", timeoutAfter, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SSHClient) runProbe(ctx context.Context, ip string, probe config.ReadinessProbe) error {
+	command, err := probeCommand(probe)
+	if err != nil {
+		return err
+	}
+	output, err := s.ExecuteWithOutput(ctx, ip, command)
+	if ctx.Err() != nil {
+		return fmt.Errorf("stopped: %w", ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("%w (output: %s)", err, output)
+	}
+	return nil
+}
+
+// probeCommand returns the guest shell command implementing a probe
+func probeCommand(probe config.ReadinessProbe) (string, error) {
+	switch probe.Type {
+	case config.ProbeRunnerBinary:
+		return "test -x ./actions-runner/run.sh && test -x ./actions-runner/config.sh", nil
+	case config.ProbeDisk:
+		return "test -d " + shellQuote(probe.Target) + " && test -w " + shellQuote(probe.Target), nil
+	case config.ProbeNetwork:
+		return "curl -sS -o /dev/null --max-time 5 " + shellQuote(probe.Target), nil
+	case config.ProbeCommand:
+		return probe.Command, nil
+	default:
+		return "", fmt.Errorf("unknown probe type %q", probe.Type)
+	}
+}
+
+func probeName(probe config.ReadinessProbe) string {
+	if probe.Name != "" {
+		return probe.Name
+	}
+	if probe.Target != "" {
+		return probe.Type + ":" + probe.Target
+	}
+	return probe.Type
+}
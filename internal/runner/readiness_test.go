@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestWaitForReadyStopsHungProbe(t *testing.T) {
+	// The probe's ssh session never returns, like a stalled connection
+	fakeCommand(t, "sshpass", "sleep 30\n")
+	cfg := testConfig(t)
+	cfg.VM.Boot.ProbeTimeout = "200ms"
+	cfg.VM.Boot.ProbePollInterval = "10ms"
+	cfg.VM.Readiness = []config.ReadinessProbe{{Name: "hangs", Type: config.ProbeCommand, Command: "sleep 3600"}}
+
+	start := time.Now()
+	err := NewSSHClient(cfg, zap.NewNop()).WaitForReady(context.Background(), "192.168.64.2")
+	if err == nil || !strings.Contains(err.Error(), "timeout waiting for readiness after 200ms") {
+		t.Errorf("WaitForReady() = %v, want a readiness timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond+sshWaitDelay+time.Second {
+		t.Errorf("WaitForReady() took %s with a 200ms probe_timeout", elapsed)
+	}
+}
+
+func TestWaitForReadySkipsPassedProbes(t *testing.T) {
+	// Fails on the first call and passes afterwards for every probe
+	state := t.TempDir()
+	fakeCommand(t, "sshpass", `echo "$*" >> `+state+`/calls
+[ -f `+state+`/seen ] && exit 0
+touch `+state+`/seen
+exit 1
+`)
+	cfg := testConfig(t)
+	cfg.VM.Boot.ProbeTimeout = "5s"
+	cfg.VM.Boot.ProbePollInterval = "10ms"
+	cfg.VM.Readiness = []config.ReadinessProbe{
+		{Type: config.ProbeRunnerBinary},
+		{Type: config.ProbeNetwork, Target: "https://github.com"},
+	}
+
+	if err := NewSSHClient(cfg, zap.NewNop()).WaitForReady(context.Background(), "192.168.64.2"); err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Count(readTestFile(t, state+"/calls"), "\n")
+	// runner_binary fails, network passes, then only runner_binary is retried
+	if calls != 3 {
+		t.Errorf("ssh ran %d times, want 3", calls)
+	}
+}
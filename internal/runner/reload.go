@@ -0,0 +1,218 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"github.com/rxtech-lab/rvmm/internal/setup"
+	"go.uber.org/zap"
+)
+
+// ConfigLoader loads a fresh copy of the configuration for a reload
+type ConfigLoader func() (*config.Config, error)
+
+// reloadableFields can change while the runner is running. Each run reads
+// them when it starts, so in-flight jobs keep the values they started with.
+// Any other change needs a restart.
+var reloadableFields = []string{
+	"github.runner_labels",
+	"github.runner_group",
+	"vm.boot",
+	"vm.readiness_probes",
+	"vm.bootstrap",
+	"options.max_concurrent_runners",
+	"options.schedule",
+	"options.hooks",
+	"options.diagnostics",
+	"options.shutdown_flag_file",
+	"options.job_timeout",
+	"options.log_file",
+}
+
+// reloader swaps in reloaded configs, keeping the current one when the new
+// one is invalid
+type reloader struct {
+	load     ConfigLoader
+	live     *atomic.Pointer[config.Config]
+	capacity *Capacity
+	logs     *logFile
+	log      *zap.Logger
+	mu       sync.Mutex
+
+	// files is the last config loaded, whose files are watched even when
+	// none of its changes could be applied
+	files atomic.Pointer[config.Config]
+	// filesChanged is signalled when files is replaced
+	filesChanged chan struct{}
+}
+
+func newReloader(load ConfigLoader, live *atomic.Pointer[config.Config], capacity *Capacity, logs *logFile, log *zap.Logger) *reloader {
+	r := &reloader{
+		load:         load,
+		live:         live,
+		capacity:     capacity,
+		logs:         logs,
+		log:          log.With(zap.String("component", "reload")),
+		filesChanged: make(chan struct{}, 1),
+	}
+	r.files.Store(live.Load())
+	return r
+}
+
+// reload loads, validates and applies the config
+func (r *reloader) reload(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.Info("Reloading config", zap.String("reason", reason))
+
+	next, err := r.load()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		r.log.Error("Config reload failed, keeping current config", zap.Error(err))
+		return
+	}
+
+	for _, warning := range next.Warnings {
+		r.log.Warn("Config warning", zap.String("warning", warning))
+	}
+
+	// Includes and overlays may have been added or removed
+	r.files.Store(next)
+	select {
+	case r.filesChanged <- struct{}{}:
+	default:
+	}
+
+	current := r.live.Load()
+	changed := config.Diff(current, next)
+	if len(changed) == 0 {
+		r.log.Info("Config unchanged")
+		return
+	}
+
+	merged := *current
+	var applied, rejected []string
+	for _, path := range changed {
+		if !reloadable(path) {
+			rejected = append(rejected, path)
+			continue
+		}
+		if err := merged.CopyField(next, path); err != nil {
+			r.log.Error("Config reload failed, keeping current config", zap.Error(err))
+			return
+		}
+		applied = append(applied, path)
+	}
+
+	if len(rejected) > 0 {
+		r.log.Warn("Ignoring config changes that require a restart",
+			zap.Strings("fields", rejected),
+		)
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	if merged.VM.CPU > 0 || merged.VM.MemoryMB > 0 {
+		if cpus, memoryMB, err := setup.HostCapacity(); err == nil {
+			if err := merged.ValidateCapacity(cpus, memoryMB); err != nil {
+				r.log.Error("Config reload failed, keeping current config", zap.Error(err))
+				return
+			}
+		}
+	}
+
+	if LogFilePath(&merged) != LogFilePath(current) {
+		if err := r.logs.open(LogFilePath(&merged)); err != nil {
+			r.log.Error("Config reload failed, keeping current config", zap.Error(err))
+			return
+		}
+	}
+
+	r.live.Store(&merged)
+	r.capacity.SetConfig(&merged)
+	r.log.Info("Applied config changes", zap.Strings("fields", applied))
+}
+
+// watch reloads whenever a file that makes up the config changes: the
+// config file, its includes and its overlays. Editors often replace files
+// instead of writing them, so the directories are watched. The watched set
+// follows the files of the last config loaded.
+func (r *reloader) watch(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		r.log.Error("Failed to watch config file", zap.Error(err))
+		return
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	r.syncWatch(watcher, watched)
+
+	// Editors emit several events per save; reload once they settle
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if r.files.Load().Affects(event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				debounce = time.After(500 * time.Millisecond)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			r.log.Warn("Config watch error", zap.Error(err))
+		case <-r.filesChanged:
+			r.syncWatch(watcher, watched)
+		case <-debounce:
+			debounce = nil
+			r.reload("file changed")
+		}
+	}
+}
+
+// syncWatch makes watcher cover the directories of the current config files
+func (r *reloader) syncWatch(watcher *fsnotify.Watcher, watched map[string]bool) {
+	cfg := r.files.Load()
+	dirs := make(map[string]bool)
+	for _, dir := range cfg.WatchDirs() {
+		dirs[dir] = true
+		if watched[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			r.log.Error("Failed to watch config directory", zap.String("path", dir), zap.Error(err))
+			continue
+		}
+		watched[dir] = true
+	}
+	for dir := range watched {
+		if !dirs[dir] {
+			watcher.Remove(dir)
+			delete(watched, dir)
+		}
+	}
+	r.log.Info("Watching config file", zap.String("path", cfg.Source), zap.Strings("layers", cfg.Layers))
+}
+
+func reloadable(path string) bool {
+	for _, field := range reloadableFields {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+	return false
+}
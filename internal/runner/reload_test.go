@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// testConfig returns a valid config working in a temporary directory
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.GitHub.APIToken = "ghp_test"
+	cfg.GitHub.RegistrationEndpoint = "https://api.github.com/orgs/acme/actions/runners/registration-token"
+	cfg.GitHub.RunnerURL = "https://github.com/acme"
+	cfg.Registry.ImageName = "runner:latest"
+	cfg.Options.WorkingDirectory = t.TempDir()
+	return cfg
+}
+
+func newTestReloader(t *testing.T, cfg *config.Config, next func() *config.Config) (*reloader, *logFile, *zap.Logger) {
+	t.Helper()
+	logs := &logFile{}
+	if err := logs.open(LogFilePath(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logs.Close() })
+	log := logs.tee(zap.NewNop())
+
+	var live atomic.Pointer[config.Config]
+	live.Store(cfg)
+	capacity := NewCapacity(cfg, log, NewSlotPool(0))
+	load := func() (*config.Config, error) { return next(), nil }
+	return newReloader(load, &live, capacity, logs, log), logs, log
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReloadSwitchesLogFile(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Options.LogFile = "first.log"
+	next := *cfg
+	next.Options.LogFile = "logs/second.log"
+
+	r, _, log := newTestReloader(t, cfg, func() *config.Config { c := next; return &c })
+	log.Info("before reload")
+	r.reload("test")
+	log.Info("after reload")
+
+	first := readLog(t, filepath.Join(cfg.Options.WorkingDirectory, "first.log"))
+	second := readLog(t, filepath.Join(cfg.Options.WorkingDirectory, "logs", "second.log"))
+	if !strings.Contains(first, "before reload") || strings.Contains(first, "after reload") {
+		t.Errorf("first.log = %q", first)
+	}
+	if !strings.Contains(second, "after reload") {
+		t.Errorf("second.log = %q", second)
+	}
+	if got := r.live.Load().Options.LogFile; got != "logs/second.log" {
+		t.Errorf("live log_file = %q", got)
+	}
+}
+
+func TestReloadDisablesLogFile(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Options.LogFile = "runner.log"
+	next := *cfg
+	next.Options.LogFile = ""
+
+	r, _, log := newTestReloader(t, cfg, func() *config.Config { c := next; return &c })
+	r.reload("test")
+	log.Info("after reload")
+
+	if got := readLog(t, filepath.Join(cfg.Options.WorkingDirectory, "runner.log")); strings.Contains(got, "after reload") {
+		t.Errorf("runner.log still written after log_file was cleared: %q", got)
+	}
+}
+
+func TestReloadLogFileWithRestartOnlyChange(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Options.LogFile = "runner.log"
+	next := *cfg
+	next.Options.LogFile = "other.log"
+	// working_directory needs a restart; log_file still moves, relative to
+	// the current working directory
+	next.Options.WorkingDirectory = t.TempDir()
+
+	r, _, log := newTestReloader(t, cfg, func() *config.Config { c := next; return &c })
+	r.reload("test")
+	log.Info("after reload")
+
+	if got := readLog(t, filepath.Join(cfg.Options.WorkingDirectory, "other.log")); !strings.Contains(got, "after reload") {
+		t.Errorf("other.log = %q; log_file is reloadable on its own", got)
+	}
+	if got := r.live.Load().Options.WorkingDirectory; got != cfg.Options.WorkingDirectory {
+		t.Errorf("working_directory changed live to %q", got)
+	}
+}
+
+func TestReloadTracksConfigFiles(t *testing.T) {
+	cfg := testConfig(t)
+	next := *cfg
+	next.Layers = []string{"/etc/rvmm/base.yaml", "/etc/rvmm/rvmm.yaml"}
+
+	r, _, _ := newTestReloader(t, cfg, func() *config.Config { c := next; return &c })
+	r.reload("test")
+
+	if got := r.files.Load().Layers; len(got) != 2 {
+		t.Errorf("watched layers = %q, want the reloaded ones", got)
+	}
+	select {
+	case <-r.filesChanged:
+	default:
+		t.Error("reload did not signal a change of config files")
+	}
+}
@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// resizeMetadataFile records the last applied resize next to the cached disk
+const resizeMetadataFile = "rvmm-resize.json"
+
+// The guest container may be smaller than the disk image because of the
+// boot and recovery partitions; accept anything above this fraction
+const minContainerFraction = 0.9
+
+var (
+	// The plist keys don't depend on the guest's locale
+	diskSizePlistRegex = regexp.MustCompile(`<key>(?:TotalSize|Size)</key>\s*<integer>(\d+)</integer>`)
+	diskSizeBytesRegex = regexp.MustCompile(`(?:Disk|Total) Size:.*?\((\d+) Bytes\)`)
+)
+
+// ResizeMetadata describes a disk resize applied to a cached image
+type ResizeMetadata struct {
+	TruncateSize   string    `json:"truncate_size"`
+	TargetBytes    int64     `json:"target_bytes"`
+	ContainerBytes int64     `json:"container_bytes"`
+	AppliedAt      time.Time `json:"applied_at"`
+}
+
+// EnsureDiskSize grows the cached image disk to options.truncate_size and
+// expands the guest APFS container to match. It is a no-op when the size has
+// already been applied to this image.
+func (v *VMManager) EnsureDiskSize(ctx context.Context) error {
+	if v.cfg.Options.TruncateSize == "" {
+		return nil
+	}
+
+	targetBytes, err := config.ParseSize(v.cfg.Options.TruncateSize)
+	if err != nil {
+		return err
+	}
+
+	cacheDir := v.GetCachePath()
+	diskPath := filepath.Join(cacheDir, "disk.img")
+	info, err := os.Stat(diskPath)
+	if os.IsNotExist(err) {
+		v.log.Info("No cached image disk to resize", zap.String("path", diskPath))
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to stat disk image: %w", err)
+	}
+
+	if meta, err := readResizeMetadata(cacheDir); err == nil &&
+		meta.TargetBytes == targetBytes && info.Size() >= targetBytes {
+		v.log.Info("Disk resize already applied, skipping",
+			zap.String("size", meta.TruncateSize),
+			zap.Int64("container_bytes", meta.ContainerBytes),
+			zap.Time("applied_at", meta.AppliedAt),
+		)
+		return nil
+	}
+
+	containerBytes, err := v.resizeDisk(ctx, diskPath, info.Size(), targetBytes)
+	if err != nil {
+		return err
+	}
+
+	meta := ResizeMetadata{
+		TruncateSize:   v.cfg.Options.TruncateSize,
+		TargetBytes:    targetBytes,
+		ContainerBytes: containerBytes,
+		AppliedAt:      time.Now().UTC(),
+	}
+	if err := writeResizeMetadata(cacheDir, meta); err != nil {
+		v.log.Warn("Failed to record disk resize metadata", zap.Error(err))
+	}
+
+	v.log.Info("Disk resized successfully",
+		zap.String("size", v.cfg.Options.TruncateSize),
+		zap.Int64("container_bytes", containerBytes),
+	)
+	return nil
+}
+
+// resizeDisk grows the disk file, boots a temporary clone to expand the APFS
+// container, verifies the result and copies the disk back into the cache.
+// It returns the container size reported by the guest.
+func (v *VMManager) resizeDisk(ctx context.Context, diskPath string, currentBytes, targetBytes int64) (int64, error) {
+	v.log.Info("Resizing cached image disk", zap.String("size", v.cfg.Options.TruncateSize))
+
+	// Never shrink: truncate would destroy data past the new end
+	if currentBytes < targetBytes {
+		cmd := exec.CommandContext(ctx, "truncate", "-s", strconv.FormatInt(targetBytes, 10), diskPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return 0, fmt.Errorf("truncate failed: %w\nOutput: %s", err, string(output))
+		}
+	}
+
+	tempInstance, err := tempInstanceName("rvmm-resize")
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.CommandContext(ctx, "tart", "clone", v.GetRegistryPath(), tempInstance)
+	cmd.Env = append(os.Environ(), "TART_NO_AUTO_PRUNE=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return 0, fmt.Errorf("clone for resize failed: %w\nOutput: %s", err, string(output))
+	}
+	defer v.Cleanup(ctx, tempInstance)
+
+	bootCmd := exec.CommandContext(ctx, "tart", "run", "--no-graphics", tempInstance)
+	if err := bootCmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start temp VM: %w", err)
+	}
+	bootDone := make(chan error, 1)
+	go func() {
+		bootDone <- bootCmd.Wait()
+	}()
+
+	ip, err := v.waitForIP(ctx, tempInstance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get temp VM IP: %w", err)
+	}
+
+	ssh := NewSSHClient(v.cfg, v.log)
+	if err := ssh.WaitForSSH(ctx, ip); err != nil {
+		return 0, fmt.Errorf("SSH not available on temp VM: %w", err)
+	}
+
+	if output, err := ssh.ExecuteWithOutput(ctx, ip, "echo y | diskutil repairDisk disk0"); err != nil {
+		return 0, fmt.Errorf("diskutil repairDisk failed: %w (output: %s)", err, output)
+	}
+	if output, err := ssh.ExecuteWithOutput(ctx, ip, "diskutil apfs resizeContainer disk0s2 0"); err != nil {
+		return 0, fmt.Errorf("diskutil apfs resizeContainer failed: %w (output: %s)", err, output)
+	}
+
+	output, err := ssh.ExecuteWithOutput(ctx, ip, "diskutil info -plist disk0s2")
+	if err != nil {
+		return 0, fmt.Errorf("diskutil info failed: %w (output: %s)", err, output)
+	}
+	containerBytes, err := parseDiskSizeBytes(output)
+	if err != nil {
+		return 0, err
+	}
+	if float64(containerBytes) < float64(targetBytes)*minContainerFraction {
+		return 0, fmt.Errorf("container is %d bytes after resize, expected close to %d", containerBytes, targetBytes)
+	}
+
+	if err := v.Stop(ctx, tempInstance); err != nil {
+		return 0, fmt.Errorf("failed to stop temp VM: %w", err)
+	}
+	select {
+	case <-bootDone:
+	case <-time.After(30 * time.Second):
+		return 0, fmt.Errorf("temp VM did not exit in time")
+	}
+
+	// Copy the resized disk next to the original, then swap it in atomically
+	tempDiskPath := filepath.Join(os.Getenv("HOME"), ".tart", "vms", tempInstance, "disk.img")
+	stagedPath := diskPath + ".resized"
+	cmd = exec.CommandContext(ctx, "cp", "-c", tempDiskPath, stagedPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return 0, fmt.Errorf("failed to copy resized disk: %w\nOutput: %s", err, string(output))
+	}
+	if err := os.Rename(stagedPath, diskPath); err != nil {
+		os.Remove(stagedPath)
+		return 0, fmt.Errorf("failed to replace disk image: %w", err)
+	}
+
+	return containerBytes, nil
+}
+
+// parseDiskSizeBytes extracts the byte count from `diskutil info -plist`
+// output, falling back to the text form, e.g.
+// "Disk Size: 214.7 GB (214748364800 Bytes) ..."
+func parseDiskSizeBytes(output string) (int64, error) {
+	m := diskSizePlistRegex.FindStringSubmatch(output)
+	if m == nil {
+		m = diskSizeBytesRegex.FindStringSubmatch(output)
+	}
+	if m == nil {
+		return 0, fmt.Errorf("could not find a disk size in bytes in diskutil output")
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid disk size %q: %w", m[1], err)
+	}
+	return n, nil
+}
+
+func readResizeMetadata(cacheDir string) (*ResizeMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(cacheDir, resizeMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var meta ResizeMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func writeResizeMetadata(cacheDir string, meta ResizeMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(cacheDir, resizeMetadataFile), data, 0644)
+}
+
+// tempInstanceName returns a VM name that won't collide with other processes
+func tempInstanceName(prefix string) (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate instance name: %w", err)
+	}
+	return fmt.Sprintf("%s-%d-%s", prefix, os.Getpid(), hex.EncodeToString(buf)), nil
+}
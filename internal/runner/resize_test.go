@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDiskSizeBytes(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    int64
+		wantErr bool
+	}{
+		{"info-plist.xml", 214748364800, false},
+		{"info-en.txt", 214748364800, false},
+		// Older macOS releases label the field Total Size
+		{"info-total-size.txt", 107374182400, false},
+		// Localized text output can't be parsed, which is why -plist is used
+		{"info-de.txt", 0, true},
+		{"info-no-bytes.txt", 0, true},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", "diskutil", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseDiskSizeBytes(string(data))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseDiskSizeBytes = %d, want an error", tt.fixture, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: parseDiskSizeBytes = %d, %v; want %d", tt.fixture, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"sync"
+)
+
+// SlotPool hands out runner slot IDs and can be resized while runs are in
+// flight. Slots 0..size-1 are usable; when the pool shrinks, busy slots above
+// the new size finish their current job and are then retired.
+type SlotPool struct {
+	mu   sync.Mutex
+	size int
+	busy map[int]bool
+	wake chan struct{}
+}
+
+// NewSlotPool creates a pool with the given number of slots
+func NewSlotPool(size int) *SlotPool {
+	return &SlotPool{
+		size: size,
+		busy: make(map[int]bool),
+		wake: make(chan struct{}),
+	}
+}
+
+// Acquire blocks until a slot is free and returns its ID. After a shrink no
+// slot is handed out until the retiring slots have drained below the new size.
+func (p *SlotPool) Acquire(ctx context.Context) (int, error) {
+	for {
+		p.mu.Lock()
+		if len(p.busy) < p.size {
+			for id := 0; id < p.size; id++ {
+				if !p.busy[id] {
+					p.busy[id] = true
+					p.mu.Unlock()
+					return id, nil
+				}
+			}
+		}
+		wake := p.wake
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release returns a slot after its run finished
+func (p *SlotPool) Release(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.busy, id)
+	p.notify()
+}
+
+// Resize changes the number of slots. Growing takes effect immediately;
+// shrinking never interrupts a running job.
+func (p *SlotPool) Resize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = size
+	p.notify()
+}
+
+// Size returns the current number of slots
+func (p *SlotPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Active returns the number of slots running a job, including retiring ones
+func (p *SlotPool) Active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.busy)
+}
+
+// Changed returns a channel that is closed the next time a slot is released
+// or the pool is resized
+func (p *SlotPool) Changed() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.wake
+}
+
+// notify wakes every goroutine blocked in Acquire; p.mu must be held
+func (p *SlotPool) notify() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
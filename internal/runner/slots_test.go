@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlotPoolAcquireRelease(t *testing.T) {
+	p := NewSlotPool(2)
+	ctx := context.Background()
+
+	a, err := p.Acquire(ctx)
+	if err != nil || a != 0 {
+		t.Fatalf("Acquire() = %d, %v; want 0", a, err)
+	}
+	b, err := p.Acquire(ctx)
+	if err != nil || b != 1 {
+		t.Fatalf("Acquire() = %d, %v; want 1", b, err)
+	}
+	if got := p.Active(); got != 2 {
+		t.Fatalf("Active() = %d, want 2", got)
+	}
+
+	p.Release(a)
+	if id, err := p.Acquire(ctx); err != nil || id != 0 {
+		t.Fatalf("Acquire() after release = %d, %v; want 0", id, err)
+	}
+}
+
+func TestSlotPoolShrinkWhileBusy(t *testing.T) {
+	p := NewSlotPool(4)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if _, err := p.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Slots 0 and 1 finish, then the pool shrinks to 2 while 2 and 3 still run
+	p.Release(0)
+	p.Release(1)
+	p.Resize(2)
+
+	if id, ok := tryAcquire(p); ok {
+		t.Fatalf("Acquire() = %d while 2 retiring slots are busy; want to block", id)
+	}
+
+	// One retiring slot drains: a single slot below the new size opens up
+	p.Release(3)
+	id, ok := tryAcquire(p)
+	if !ok || id != 0 {
+		t.Fatalf("Acquire() = %d, %v after drain; want 0", id, ok)
+	}
+	if id, ok := tryAcquire(p); ok {
+		t.Fatalf("Acquire() = %d with %d active and size 2; want to block", id, p.Active())
+	}
+
+	p.Release(2)
+	id, ok = tryAcquire(p)
+	if !ok || id != 1 {
+		t.Fatalf("Acquire() = %d, %v; want 1", id, ok)
+	}
+	if got := p.Active(); got != 2 {
+		t.Fatalf("Active() = %d, want 2", got)
+	}
+}
+
+func TestSlotPoolGrowWakesAcquire(t *testing.T) {
+	p := NewSlotPool(0)
+	done := make(chan int, 1)
+	go func() {
+		id, _ := p.Acquire(context.Background())
+		done <- id
+	}()
+
+	p.Resize(1)
+	select {
+	case id := <-done:
+		if id != 0 {
+			t.Fatalf("Acquire() = %d, want 0", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() did not return after the pool grew")
+	}
+}
+
+// tryAcquire acquires a slot unless Acquire would block
+func tryAcquire(p *SlotPool) (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	id, err := p.Acquire(ctx)
+	return id, err == nil
+}
+
+func TestWaitForSlotSeesShutdownWithoutCapacity(t *testing.T) {
+	// A maintenance window: no slots for as long as it lasts
+	p := NewSlotPool(0)
+	var requested atomic.Bool
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		requested.Store(true)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := waitForSlot(ctx, p, 10*time.Millisecond, requested.Load)
+	if !errors.Is(err, errShutdownRequested) {
+		t.Fatalf("waitForSlot() = %v, want errShutdownRequested", err)
+	}
+}
+
+func TestWaitForSlot(t *testing.T) {
+	p := NewSlotPool(0)
+	never := func() bool { return false }
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		p.Resize(1)
+	}()
+	if id, err := waitForSlot(context.Background(), p, 10*time.Millisecond, never); err != nil || id != 0 {
+		t.Fatalf("waitForSlot() = %d, %v; want 0", id, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := waitForSlot(ctx, p, 10*time.Millisecond, never); !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForSlot() after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestShutdownRequested(t *testing.T) {
+	cfg := testConfig(t)
+	if shutdownRequested(cfg) {
+		t.Error("shutdown requested without a flag file configured")
+	}
+	cfg.Options.ShutdownFlagFile = filepath.Join(t.TempDir(), ".shutdown")
+	if shutdownRequested(cfg) {
+		t.Error("shutdown requested before the flag file exists")
+	}
+	if err := os.WriteFile(cfg.Options.ShutdownFlagFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !shutdownRequested(cfg) {
+		t.Error("shutdown not requested with the flag file present")
+	}
+}
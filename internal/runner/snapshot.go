@@ -0,0 +1,268 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// snapshotMetadataFile records which image a snapshot was built from
+const snapshotMetadataFile = "snapshot.json"
+
+// SnapshotMetadata identifies the image and settings a snapshot was built from
+type SnapshotMetadata struct {
+	Name      string    `json:"name"`
+	Identity  string    `json:"identity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Snapshot manages a suspended, fully booted VM that runner clones resume
+// from instead of cold booting. It is shared by all workers.
+type Snapshot struct {
+	cfg  *config.Config
+	log  *zap.Logger
+	name string
+
+	mu        sync.RWMutex
+	ready     bool
+	builtFrom string
+}
+
+// NewSnapshot creates a snapshot manager for the configured image
+func NewSnapshot(cfg *config.Config, log *zap.Logger) *Snapshot {
+	return &Snapshot{
+		cfg:  cfg,
+		log:  log.With(zap.String("snapshot", "rvmm-snapshot-"+cfg.GitHub.RunnerName)),
+		name: "rvmm-snapshot-" + cfg.GitHub.RunnerName,
+	}
+}
+
+// Name returns the Tart VM name of the suspended snapshot
+func (s *Snapshot) Name() string {
+	return s.name
+}
+
+// Ready reports whether clones can resume from the snapshot
+func (s *Snapshot) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ready
+}
+
+// Identity returns the identity of the ready snapshot, or "" when clones
+// cold boot
+func (s *Snapshot) Identity() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.ready {
+		return ""
+	}
+	return s.builtFrom
+}
+
+// Ensure reuses the existing snapshot when it was built from the current
+// image and settings, and rebuilds it otherwise. vm must have resolved its
+// image via ImageExists.
+func (s *Snapshot) Ensure(ctx context.Context, vm *VMManager) error {
+	identity, err := s.identity(vm)
+	if err != nil {
+		return fmt.Errorf("failed to identify image: %w", err)
+	}
+
+	if meta, err := s.readMetadata(); err == nil && meta.Identity == identity && s.exists() {
+		s.log.Info("Reusing suspended snapshot", zap.Time("created_at", meta.CreatedAt))
+		s.setReady(true, identity)
+		return nil
+	}
+
+	s.Invalidate(ctx, vm)
+	if err := s.build(ctx, vm); err != nil {
+		vm.Cleanup(ctx, s.name)
+		return err
+	}
+
+	meta := SnapshotMetadata{Name: s.name, Identity: identity, CreatedAt: time.Now().UTC()}
+	if err := s.writeMetadata(meta); err != nil {
+		s.log.Warn("Failed to record snapshot metadata", zap.Error(err))
+	}
+	s.setReady(true, identity)
+	return nil
+}
+
+// Invalidate marks the snapshot unusable and deletes it
+func (s *Snapshot) Invalidate(ctx context.Context, vm *VMManager) {
+	s.setReady(false, "")
+	if s.exists() {
+		s.log.Info("Removing stale snapshot")
+		vm.Cleanup(ctx, s.name)
+	}
+	os.Remove(s.metadataPath())
+}
+
+// build clones the image, boots it to a ready state and suspends it
+func (s *Snapshot) build(ctx context.Context, vm *VMManager) error {
+	s.log.Info("Building suspended snapshot")
+
+	if err := vm.Clone(ctx, s.name); err != nil {
+		return fmt.Errorf("failed to clone snapshot VM: %w", err)
+	}
+	if err := vm.ApplyResources(ctx, s.name); err != nil {
+		return fmt.Errorf("failed to size snapshot VM: %w", err)
+	}
+	if vm.cfg.VM.Display != "" {
+		cmd := exec.CommandContext(ctx, "tart", "set", s.name, "--display", vm.cfg.VM.Display)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set snapshot display: %w\nOutput: %s", err, string(output))
+		}
+	}
+
+	runCmd, err := vm.run(ctx, s.name, "--suspendable")
+	if err != nil {
+		return fmt.Errorf("failed to start snapshot VM: %w", err)
+	}
+	runDone := make(chan error, 1)
+	go func() {
+		runDone <- runCmd.Wait()
+	}()
+
+	ip, err := vm.WaitForIP(ctx, s.name)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot VM IP: %w", err)
+	}
+
+	ssh := NewSSHClient(s.cfg, s.log)
+	if err := ssh.WaitForSSH(ctx, ip); err != nil {
+		return fmt.Errorf("SSH not available on snapshot VM: %w", err)
+	}
+	if err := ssh.WaitForReady(ctx, ip); err != nil {
+		return fmt.Errorf("snapshot VM not ready: %w", err)
+	}
+
+	s.log.Info("Suspending snapshot VM")
+	cmd := exec.CommandContext(ctx, "tart", "suspend", s.name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tart suspend failed: %w\nOutput: %s", err, string(output))
+	}
+
+	select {
+	case <-runDone:
+	case <-time.After(60 * time.Second):
+		return fmt.Errorf("snapshot VM did not exit after suspend")
+	}
+
+	s.log.Info("Suspended snapshot ready")
+	return nil
+}
+
+// identity hashes the source image and every setting baked into the snapshot,
+// so a new image or changed VM settings invalidate it
+func (s *Snapshot) identity(vm *VMManager) (string, error) {
+	h := sha256.New()
+	if err := writeImageIdentity(h, vm); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "cpu=%d memory=%d disk_size=%d\n", s.cfg.VM.CPU, s.cfg.VM.MemoryMB, s.cfg.VM.DiskSizeGB)
+	fmt.Fprintf(h, "display=%s\n", s.cfg.VM.Display)
+	fmt.Fprintf(h, "network=%s %v %s\n", s.cfg.VM.Network.Mode, s.cfg.VM.Network.Allow, s.cfg.VM.Network.Interface)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeImageIdentity writes the image vm clones from, as resolved on disk,
+// so a re-pulled or rebuilt image changes it
+func writeImageIdentity(w io.Writer, vm *VMManager) error {
+	source := vm.imageRef
+	if source == "" {
+		source = vm.GetRegistryPath()
+	}
+
+	imageDir := vm.GetCachePath()
+	if _, err := os.Stat(imageDir); err != nil {
+		imageDir = filepath.Join(os.Getenv("HOME"), ".tart", "vms", source)
+	}
+	resolved, err := filepath.EvalSymlinks(imageDir)
+	if err != nil {
+		return err
+	}
+	disk, err := os.Stat(filepath.Join(resolved, "disk.img"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "source=%s\n", source)
+	fmt.Fprintf(w, "resolved=%s\n", resolved)
+	fmt.Fprintf(w, "disk=%d/%d\n", disk.Size(), disk.ModTime().UnixNano())
+	return nil
+}
+
+func (s *Snapshot) exists() bool {
+	_, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".tart", "vms", s.name))
+	return err == nil
+}
+
+func (s *Snapshot) setReady(ready bool, identity string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = ready
+	s.builtFrom = identity
+}
+
+func (s *Snapshot) metadataPath() string {
+	return filepath.Join(s.cfg.Options.WorkingDirectory, snapshotMetadataFile)
+}
+
+func (s *Snapshot) readMetadata() (*SnapshotMetadata, error) {
+	data, err := os.ReadFile(s.metadataPath())
+	if err != nil {
+		return nil, err
+	}
+	var meta SnapshotMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (s *Snapshot) writeMetadata(meta SnapshotMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.cfg.Options.WorkingDirectory, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.metadataPath(), data, 0644)
+}
+
+// Resume clones the suspended snapshot and starts it, restoring the saved
+// state. The caller falls back to a cold boot if the returned error is set.
+func (v *VMManager) Resume(ctx context.Context, instanceName string) (*exec.Cmd, error) {
+	if v.snapshot == nil || !v.snapshot.Ready() {
+		return nil, fmt.Errorf("no snapshot available")
+	}
+	if err := v.CloneFrom(ctx, v.snapshot.Name(), instanceName); err != nil {
+		return nil, err
+	}
+	v.log.Info("Resuming VM from snapshot", zap.String("instance", instanceName))
+	return v.run(ctx, instanceName, "--suspendable")
+}
+
+// UseSnapshot makes Resume available for clones of the given snapshot
+func (v *VMManager) UseSnapshot(snapshot *Snapshot) {
+	v.snapshot = snapshot
+}
+
+// CanResume reports whether a ready snapshot is available
+func (v *VMManager) CanResume() bool {
+	return v.snapshot != nil && v.snapshot.Ready()
+}
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -11,6 +12,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// sshWaitDelay bounds how long a cancelled ssh command may keep its output
+// open, since killing sshpass leaves the ssh process behind
+const sshWaitDelay = time.Second
+
 // SSHClient handles SSH command execution on VMs
 type SSHClient struct {
 	cfg *config.Config
@@ -27,29 +32,25 @@ func NewSSHClient(cfg *config.Config, log *zap.Logger) *SSHClient {
 
 // WaitForSSH polls until SSH is available on the VM
 func (s *SSHClient) WaitForSSH(ctx context.Context, ip string) error {
-	s.log.Info("Waiting for SSH to be available", zap.String("ip", ip))
+	timeoutAfter := s.cfg.VM.Boot.SSHTimeoutDuration()
+	s.log.Info("Waiting for SSH to be available",
+		zap.String("ip", ip),
+		zap.Duration("timeout", timeoutAfter),
+	)
 
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(s.cfg.VM.Boot.SSHPollIntervalDuration())
 	defer ticker.Stop()
 
-	timeout := time.After(5 * time.Minute)
+	timeout := time.After(timeoutAfter)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-timeout:
-			return fmt.Errorf("timeout waiting for SSH")
+			return fmt.Errorf("timeout waiting for SSH after %s", timeoutAfter)
 		case <-ticker.C:
-			cmd := exec.CommandContext(ctx, "sshpass", "-e", "ssh",
-				"-q",
-				"-o", "ConnectTimeout=1",
-				"-o", "StrictHostKeyChecking=no",
-				fmt.Sprintf("%s@%s", s.cfg.VM.Username, ip),
-				"pwd",
-			)
-			cmd.Env = append(os.Environ(), "SSHPASS="+s.cfg.VM.Password)
-
+			cmd := s.command(ctx, ip, "pwd", "-o", "ConnectTimeout=1")
 			if err := cmd.Run(); err == nil {
 				s.log.Info("SSH is available")
 				return nil
@@ -65,14 +66,7 @@ func (s *SSHClient) Execute(ctx context.Context, ip string, command string, show
 		zap.String("command", command),
 	)
 
-	cmd := exec.CommandContext(ctx, "sshpass", "-e", "ssh",
-		"-q",
-		"-o", "StrictHostKeyChecking=no",
-		fmt.Sprintf("%s@%s", s.cfg.VM.Username, ip),
-		command,
-	)
-	cmd.Env = append(os.Environ(), "SSHPASS="+s.cfg.VM.Password)
-
+	cmd := s.command(ctx, ip, command)
 	if showOutput {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -92,13 +86,8 @@ func (s *SSHClient) ExecuteWithOutput(ctx context.Context, ip string, command st
 		zap.String("command", command),
 	)
 
-	cmd := exec.CommandContext(ctx, "sshpass", "-e", "ssh",
-		"-q",
-		"-o", "StrictHostKeyChecking=no",
-		fmt.Sprintf("%s@%s", s.cfg.VM.Username, ip),
-		command,
-	)
-	cmd.Env = append(os.Environ(), "SSHPASS="+s.cfg.VM.Password)
+	cmd := s.command(ctx, ip, command)
+	cmd.WaitDelay = sshWaitDelay
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -108,6 +97,37 @@ func (s *SSHClient) ExecuteWithOutput(ctx context.Context, ip string, command st
 	return string(output), nil
 }
 
+// ExecuteStdout runs a command and returns its standard output unchanged,
+// for binary data such as a tar stream
+func (s *SSHClient) ExecuteStdout(ctx context.Context, ip string, command string) ([]byte, error) {
+	s.log.Debug("Executing SSH command",
+		zap.String("ip", ip),
+		zap.String("command", command),
+	)
+
+	var stderr bytes.Buffer
+	cmd := s.command(ctx, ip, command)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = sshWaitDelay
+
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("SSH command failed: %w\nOutput: %s", err, stderr.String())
+	}
+
+	return output, nil
+}
+
+// command builds an sshpass-wrapped ssh invocation of remote on the VM. opts
+// are extra ssh arguments placed before the destination.
+func (s *SSHClient) command(ctx context.Context, ip string, remote string, opts ...string) *exec.Cmd {
+	args := append([]string{"-e", "ssh", "-q", "-o", "StrictHostKeyChecking=no"}, opts...)
+	args = append(args, fmt.Sprintf("%s@%s", s.cfg.VM.Username, ip), remote)
+	cmd := exec.CommandContext(ctx, "sshpass", args...)
+	cmd.Env = append(os.Environ(), "SSHPASS="+s.cfg.VM.Password)
+	return cmd
+}
+
 // ConfigureRunner sets up the GitHub Actions runner on the VM
 func (s *SSHClient) ConfigureRunner(ctx context.Context, ip string, token string, runnerName string) error {
 	s.log.Info("Configuring GitHub Actions runner", zap.String("runner_name", runnerName))
@@ -128,7 +148,7 @@ func (s *SSHClient) ConfigureRunner(ctx context.Context, ip string, token string
 
 	// Build config command
 	configCmd := fmt.Sprintf(
-		"./actions-runner/config.sh --url %s --token %s --ephemeral --name %s --labels %s --unattended --replace",
+		"./actions-runner/config.sh --url %s --token %s --ephemeral --name %s --labels %s --unattended",
 		s.cfg.GitHub.RunnerURL,
 		token,
 		runnerName,
@@ -160,16 +180,11 @@ func (s *SSHClient) RunRunner(ctx context.Context, ip string) error {
 	runCmd := "source ~/.zprofile && ./actions-runner/run.sh"
 
 	// Use -T flag to disable TTY allocation (non-interactive)
-	cmd := exec.CommandContext(ctx, "sshpass", "-e", "ssh",
+	cmd := s.command(ctx, ip, runCmd,
 		"-T", // Disable TTY allocation for non-interactive runner execution
-		"-q",
-		"-o", "StrictHostKeyChecking=no",
 		"-o", "ServerAliveInterval=30",
 		"-o", "ServerAliveCountMax=3",
-		fmt.Sprintf("%s@%s", s.cfg.VM.Username, ip),
-		runCmd,
 	)
-	cmd.Env = append(os.Environ(), "SSHPASS="+s.cfg.VM.Password)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
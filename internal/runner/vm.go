@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +23,8 @@ type VMManager struct {
 	log *zap.Logger
 	// Resolved image ref to use for clone/run (local or registry)
 	imageRef string
+	// Suspended snapshot to resume clones from, if enabled
+	snapshot *Snapshot
 }
 
 // NewVMManager creates a new VM manager
@@ -34,25 +37,39 @@ func NewVMManager(cfg *config.Config, log *zap.Logger) *VMManager {
 
 // GetRegistryPath returns the full image path for tart commands
 func (v *VMManager) GetRegistryPath() string {
-	if v.cfg.Registry.URL == "" {
-		return v.cfg.Registry.ImageName
-	}
+	return v.cfg.Registry.Reference()
+}
 
-	// Avoid double-prefixing when image name already includes the registry host
-	registryPrefix := v.cfg.Registry.URL + "/"
-	if strings.HasPrefix(v.cfg.Registry.ImageName, registryPrefix) {
-		return v.cfg.Registry.ImageName
+// GetCachePath returns the local cache path for the image
+func (v *VMManager) GetCachePath() string {
+	repo, tag, digest := splitImageRef(v.GetRegistryPath())
+	// Tart caches a tag as a symlink and a digest as a directory, both in
+	// the repository folder
+	name := digest
+	if name == "" {
+		name = tag
+	}
+	if name == "" {
+		name = "latest"
 	}
+	return filepath.Join(ociCacheDir(), repo, name)
+}
 
-	return fmt.Sprintf("%s/%s", v.cfg.Registry.URL, v.cfg.Registry.ImageName)
+// ociCacheDir is where Tart caches pulled OCI images
+func ociCacheDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".tart", "cache", "OCIs")
 }
 
-// GetCachePath returns the local cache path for the image
-func (v *VMManager) GetCachePath() string {
-	registryPath := v.GetRegistryPath()
-	// Replace : with / for tag in path
-	cachePath := strings.ReplaceAll(registryPath, ":", "/")
-	return filepath.Join(os.Getenv("HOME"), ".tart", "cache", "OCIs", cachePath)
+// splitImageRef splits an OCI reference into its repository and its explicit
+// tag or digest; a registry port is part of the repository
+func splitImageRef(ref string) (repo, tag, digest string) {
+	if at := strings.Index(ref, "@"); at >= 0 {
+		return ref[:at], "", ref[at+1:]
+	}
+	if colon := strings.LastIndex(ref, ":"); colon > strings.LastIndex(ref, "/") {
+		return ref[:colon], ref[colon+1:], ""
+	}
+	return ref, "", ""
 }
 
 // Login authenticates with the registry if credentials are provided
@@ -113,129 +130,92 @@ func (v *VMManager) ImageExists(ctx context.Context) (bool, error) {
 
 // PullImage pulls the image from the registry
 func (v *VMManager) PullImage(ctx context.Context) error {
+	return v.PullImageWithProgress(ctx, nil)
+}
+
+// PullImageWithProgress pulls the configured image, reporting progress to
+// onProgress (which may be nil) and retrying transient failures
+func (v *VMManager) PullImageWithProgress(ctx context.Context, onProgress func(PullProgress)) error {
 	v.log.Info("Pulling VM image from registry")
 
-	// Remove old cached images
-	v.log.Info("Removing old cached images")
-	tartDir := filepath.Join(os.Getenv("HOME"), ".tart")
-	if err := os.RemoveAll(tartDir); err != nil {
-		v.log.Warn("Failed to remove old tart directory", zap.Error(err))
-	}
+	// Remove other cached tags of this image, but keep any partially pulled
+	// layers of the target so an interrupted pull can resume
+	v.pruneOtherCachedTags()
 
 	registryPath := v.GetRegistryPath()
-
-	cmd := exec.CommandContext(ctx, "tart", "pull", registryPath, "--concurrency", "1")
-
-	if v.cfg.Registry.Username != "" {
-		cmd.Env = append(os.Environ(),
-			"TART_REGISTRY_USERNAME="+v.cfg.Registry.Username,
-			"TART_REGISTRY_PASSWORD="+v.cfg.Registry.Password,
-		)
-	}
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("tart pull failed: %w", err)
+	if err := v.PullRef(ctx, registryPath, onProgress); err != nil {
+		return err
 	}
 
 	// Resize disk if configured
-	if v.cfg.Options.TruncateSize != "" {
-		if err := v.resizeCachedImage(ctx); err != nil {
-			return fmt.Errorf("disk resize failed: %w", err)
-		}
+	if err := v.EnsureDiskSize(ctx); err != nil {
+		return fmt.Errorf("disk resize failed: %w", err)
 	}
 
 	return nil
 }
 
-func (v *VMManager) resizeCachedImage(ctx context.Context) error {
-	v.log.Info("Resizing cached image disk", zap.String("size", v.cfg.Options.TruncateSize))
-
-	diskPath := filepath.Join(v.GetCachePath(), "disk.img")
-
-	// Truncate disk file
-	cmd := exec.CommandContext(ctx, "truncate", "-s", v.cfg.Options.TruncateSize, diskPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("truncate failed: %w", err)
+// Clone creates a new VM instance from the cached image
+func (v *VMManager) Clone(ctx context.Context, instanceName string) error {
+	imageRef := v.imageRef
+	if imageRef == "" {
+		imageRef = v.GetRegistryPath()
 	}
 
-	// Boot temp VM to resize partition
-	tempInstance := "truncate_instance"
-	registryPath := v.GetRegistryPath()
-
-	// Clone for resize
-	cmd = exec.CommandContext(ctx, "tart", "clone", registryPath, tempInstance)
-	cmd.Env = append(os.Environ(), "TART_NO_AUTO_PRUNE=")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("clone for resize failed: %w", err)
-	}
+	return v.CloneFrom(ctx, imageRef, instanceName)
+}
 
-	defer func() {
-		exec.CommandContext(ctx, "tart", "stop", tempInstance).Run()
-		exec.CommandContext(ctx, "tart", "delete", tempInstance).Run()
-	}()
+// CloneFrom creates a new VM instance from an image or another VM
+func (v *VMManager) CloneFrom(ctx context.Context, source string, instanceName string) error {
+	v.log.Info("Cloning VM", zap.String("instance", instanceName), zap.String("source", source))
 
-	// Boot VM
-	bootCmd := exec.CommandContext(ctx, "tart", "run", "--no-graphics", tempInstance)
-	if err := bootCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start temp VM: %w", err)
-	}
+	cmd := exec.CommandContext(ctx, "tart", "clone", source, instanceName)
+	cmd.Env = append(os.Environ(), "TART_NO_AUTO_PRUNE=")
 
-	// Wait for IP
-	ip, err := v.waitForIP(ctx, tempInstance)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("failed to get temp VM IP: %w", err)
-	}
-
-	// Wait for SSH
-	ssh := NewSSHClient(v.cfg, v.log)
-	if err := ssh.WaitForSSH(ctx, ip); err != nil {
-		return fmt.Errorf("SSH not available on temp VM: %w", err)
-	}
-
-	// Repair and resize disk
-	ssh.Execute(ctx, ip, "echo y | diskutil repairDisk disk0", false)
-	ssh.Execute(ctx, ip, "diskutil apfs resizeContainer disk0s2 0", false)
-
-	// Stop VM
-	exec.CommandContext(ctx, "tart", "stop", tempInstance).Run()
-
-	// Copy resized disk back
-	tempDiskPath := filepath.Join(os.Getenv("HOME"), ".tart", "vms", tempInstance, "disk.img")
-	os.Remove(diskPath)
-	cmd = exec.CommandContext(ctx, "cp", "-c", tempDiskPath, diskPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to copy resized disk: %w", err)
+		return fmt.Errorf("tart clone failed: %w\nOutput: %s", err, string(output))
 	}
 
-	v.log.Info("Disk resized successfully")
 	return nil
 }
 
-// Clone creates a new VM instance from the cached image
-func (v *VMManager) Clone(ctx context.Context, instanceName string) error {
-	v.log.Info("Cloning VM", zap.String("instance", instanceName))
-
-	imageRef := v.imageRef
-	if imageRef == "" {
-		imageRef = v.GetRegistryPath()
+// ApplyResources applies the vm.cpu, vm.memory_mb and vm.disk_size_gb
+// overrides to a cloned instance. Unset values keep the image defaults.
+func (v *VMManager) ApplyResources(ctx context.Context, instanceName string) error {
+	var args []string
+	if v.cfg.VM.CPU > 0 {
+		args = append(args, "--cpu", strconv.Itoa(v.cfg.VM.CPU))
+	}
+	if v.cfg.VM.MemoryMB > 0 {
+		args = append(args, "--memory", strconv.Itoa(v.cfg.VM.MemoryMB))
+	}
+	if v.cfg.VM.DiskSizeGB > 0 {
+		args = append(args, "--disk-size", strconv.Itoa(v.cfg.VM.DiskSizeGB))
+	}
+	if len(args) == 0 {
+		return nil
 	}
 
-	cmd := exec.CommandContext(ctx, "tart", "clone", imageRef, instanceName)
-	cmd.Env = append(os.Environ(), "TART_NO_AUTO_PRUNE=")
+	v.log.Info("Setting VM resources",
+		zap.String("instance", instanceName),
+		zap.Int("cpu", v.cfg.VM.CPU),
+		zap.Int("memory_mb", v.cfg.VM.MemoryMB),
+		zap.Int("disk_size_gb", v.cfg.VM.DiskSizeGB),
+	)
 
+	cmd := exec.CommandContext(ctx, "tart", append([]string{"set", instanceName}, args...)...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("tart clone failed: %w\nOutput: %s", err, string(output))
+		return fmt.Errorf("tart set failed: %w\nOutput: %s", err, string(output))
 	}
 
 	return nil
 }
 
-// Start boots a VM instance
-func (v *VMManager) Start(ctx context.Context, instanceName string) (*exec.Cmd, error) {
+// Start boots a VM instance. slotID selects the per-slot directory for
+// mounts configured with per_slot.
+func (v *VMManager) Start(ctx context.Context, instanceName string, slotID int) (*exec.Cmd, error) {
 	v.log.Info("Starting VM", zap.String("instance", instanceName))
 
 	// Set display resolution before starting
@@ -247,7 +227,20 @@ func (v *VMManager) Start(ctx context.Context, instanceName string) (*exec.Cmd,
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, "tart", "run", "--no-graphics", instanceName)
+	dirArgs, err := v.mountArgs(slotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.run(ctx, instanceName, dirArgs...)
+}
+
+// run launches `tart run` for an instance with the configured networking
+func (v *VMManager) run(ctx context.Context, instanceName string, extraArgs ...string) (*exec.Cmd, error) {
+	args := append([]string{"run", "--no-graphics"}, v.networkArgs()...)
+	args = append(args, extraArgs...)
+	args = append(args, instanceName)
+	cmd := exec.CommandContext(ctx, "tart", args...)
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("tart run failed: %w", err)
@@ -256,25 +249,81 @@ func (v *VMManager) Start(ctx context.Context, instanceName string) (*exec.Cmd,
 	return cmd, nil
 }
 
+// networkArgs translates vm.network into `tart run` networking arguments
+func (v *VMManager) networkArgs() []string {
+	network := v.cfg.VM.Network
+	switch network.Mode {
+	case config.NetworkSoftnet:
+		v.log.Info("Using softnet network isolation", zap.Strings("allow", network.Allow))
+		args := []string{"--net-softnet"}
+		if len(network.Allow) > 0 {
+			args = append(args, "--net-softnet-allow="+strings.Join(network.Allow, ","))
+		}
+		return args
+	case config.NetworkBridged:
+		v.log.Info("Using bridged networking", zap.String("interface", network.Interface))
+		return []string{"--net-bridged=" + network.Interface}
+	default:
+		return nil
+	}
+}
+
+// mountArgs translates vm.mounts into `tart run --dir` arguments, creating
+// the host directories as needed
+func (v *VMManager) mountArgs(slotID int) ([]string, error) {
+	var args []string
+	for _, mount := range v.cfg.VM.Mounts {
+		hostPath := mount.HostPath
+		if mount.PerSlot {
+			if slotID < 0 {
+				return nil, fmt.Errorf("mount %s is per_slot but the VM has no runner slot", mount.Name)
+			}
+			hostPath = filepath.Join(hostPath, fmt.Sprintf("slot-%d", slotID))
+		}
+		if err := os.MkdirAll(hostPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create mount directory %s: %w", hostPath, err)
+		}
+
+		spec := mount.Name + ":" + hostPath
+		if mount.ReadOnly {
+			spec += ":ro"
+		}
+		v.log.Info("Sharing host directory",
+			zap.String("name", mount.Name),
+			zap.String("host_path", hostPath),
+			zap.Bool("read_only", mount.ReadOnly),
+		)
+		args = append(args, "--dir", spec)
+	}
+	return args, nil
+}
+
 // WaitForIP polls until the VM has an IP address
 func (v *VMManager) WaitForIP(ctx context.Context, instanceName string) (string, error) {
 	return v.waitForIP(ctx, instanceName)
 }
 
 func (v *VMManager) waitForIP(ctx context.Context, instanceName string) (string, error) {
-	v.log.Info("Waiting for VM IP address", zap.String("instance", instanceName))
+	return v.waitForIPWithin(ctx, instanceName, v.cfg.VM.Boot.IPTimeoutDuration())
+}
+
+func (v *VMManager) waitForIPWithin(ctx context.Context, instanceName string, timeoutAfter time.Duration) (string, error) {
+	v.log.Info("Waiting for VM IP address",
+		zap.String("instance", instanceName),
+		zap.Duration("timeout", timeoutAfter),
+	)
 
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(v.cfg.VM.Boot.IPPollIntervalDuration())
 	defer ticker.Stop()
 
-	timeout := time.After(5 * time.Minute)
+	timeout := time.After(timeoutAfter)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return "", ctx.Err()
 		case <-timeout:
-			return "", fmt.Errorf("timeout waiting for VM IP")
+			return "", fmt.Errorf("timeout waiting for VM IP after %s", timeoutAfter)
 		case <-ticker.C:
 			cmd := exec.CommandContext(ctx, "tart", "ip", instanceName)
 			output, err := cmd.Output()
@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+// warmSlotID is the slot ID of a VM booted ahead of a run, before any runner
+// slot has been assigned to it
+const warmSlotID = -1
+
+// maxHostVMs is how many macOS guests Virtualization.framework runs at once
+const maxHostVMs = 2
+
+// WarmVM is a cloned, booted and SSH-ready VM waiting for a runner slot
+type WarmVM struct {
+	Name      string
+	IP        string
+	Done      <-chan error
+	CreatedAt time.Time
+	// Identity of the image and snapshot the VM was booted from
+	Identity string
+}
+
+// WarmPool keeps options.warm_pool.size VMs booted ahead of demand so a
+// freed slot only has to register the runner. It never holds more VMs than
+// there are runner slots, so a schedule window without capacity stops it, and
+// never boots more VMs than the host can run next to the active runners.
+type WarmPool struct {
+	cfg      *config.Config
+	log      *zap.Logger
+	snapshot *Snapshot
+	slots    *SlotPool
+	size     int
+	maxAge   time.Duration
+	prefix   string
+
+	ready   chan *WarmVM
+	tokens  chan struct{}
+	wake    chan struct{}
+	counter atomic.Int64
+	wg      sync.WaitGroup
+}
+
+// NewWarmPool creates a warm pool; call Run to start filling it
+func NewWarmPool(cfg *config.Config, log *zap.Logger, snapshot *Snapshot, slots *SlotPool) *WarmPool {
+	size := cfg.Options.WarmPool.Size
+	return &WarmPool{
+		cfg:      cfg,
+		log:      log.With(zap.String("component", "warm_pool")),
+		snapshot: snapshot,
+		slots:    slots,
+		size:     size,
+		maxAge:   cfg.Options.WarmPool.MaxAgeDuration(),
+		prefix:   cfg.GitHub.RunnerName + "_warm_",
+		ready:    make(chan *WarmVM, size),
+		tokens:   make(chan struct{}, size),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Run keeps the pool filled until ctx is cancelled, then deletes every warm
+// VM that was not handed out. It blocks until cleanup is complete.
+func (p *WarmPool) Run(ctx context.Context) {
+	p.removeLeftovers()
+
+	p.log.Info("Starting warm pool",
+		zap.Int("size", p.size),
+		zap.Duration("max_age", p.maxAge),
+	)
+
+	reaper := time.NewTicker(time.Minute)
+	defer reaper.Stop()
+
+	for {
+		// Only boot another VM while the current capacity has room for it
+		var tokenCh chan struct{}
+		if len(p.tokens) < p.target() {
+			tokenCh = p.tokens
+		}
+		select {
+		case <-ctx.Done():
+			p.wg.Wait()
+			p.drain()
+			p.log.Info("Warm pool stopped")
+			return
+		case <-reaper.C:
+			p.recycleStale()
+			p.trim()
+		case <-p.slots.Changed():
+			p.trim()
+		case <-p.wake:
+		case tokenCh <- struct{}{}:
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				p.warmOne(ctx)
+			}()
+		}
+	}
+}
+
+// Acquire takes a warm VM without blocking. It returns nil when none is
+// ready; expired VMs and VMs of an older image are discarded.
+func (p *WarmPool) Acquire() *WarmVM {
+	for {
+		select {
+		case w := <-p.ready:
+			if reason := p.stale(w); reason != "" {
+				p.discard(w, reason)
+				p.release()
+				continue
+			}
+			p.release()
+			p.log.Info("Acquired warm VM", zap.String("instance", w.Name), zap.Duration("age", time.Since(w.CreatedAt)))
+			return w
+		default:
+			return nil
+		}
+	}
+}
+
+// warmOne boots a single VM and adds it to the pool. The pool slot is
+// released on failure so another attempt is made.
+func (p *WarmPool) warmOne(ctx context.Context) {
+	name := fmt.Sprintf("%s%d", p.prefix, p.counter.Add(1))
+	log := p.log.With(zap.String("instance", name))
+	log.Info("Warming VM")
+	identity := p.identity()
+
+	vm := NewVMManager(p.cfg, log)
+	if p.snapshot != nil {
+		vm.UseSnapshot(p.snapshot)
+	}
+
+	fail := func(err error) {
+		vm.Cleanup(ctx, name)
+		p.release()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error("Failed to warm VM", zap.Error(err))
+		// Back off before the slot is refilled
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Second):
+		}
+	}
+
+	event := HookEvent{InstanceName: name, SlotID: warmSlotID, Outcome: OutcomePending}
+	done, ip, err := bootInstance(ctx, log, vm, nil, &event, warmSlotID)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	ssh := NewSSHClient(p.cfg, log)
+	if err := ssh.WaitForSSH(ctx, ip); err != nil {
+		fail(fmt.Errorf("SSH not available: %w", err))
+		return
+	}
+	if err := ssh.WaitForReady(ctx, ip); err != nil {
+		fail(fmt.Errorf("VM not ready: %w", err))
+		return
+	}
+
+	p.ready <- &WarmVM{Name: name, IP: ip, Done: done, CreatedAt: time.Now(), Identity: identity}
+	log.Info("Warm VM ready", zap.String("ip", ip))
+}
+
+// recycleStale replaces warm VMs older than max_age or booted from an image
+// or snapshot that has since changed
+func (p *WarmPool) recycleStale() {
+	for i := len(p.ready); i > 0; i-- {
+		select {
+		case w := <-p.ready:
+			if reason := p.stale(w); reason != "" {
+				p.discard(w, reason)
+				p.release()
+				continue
+			}
+			p.ready <- w
+		default:
+			return
+		}
+	}
+}
+
+// target is the number of warm VMs to keep: options.warm_pool.size, capped
+// by the current number of runner slots and by the VMs the host can still
+// run next to the active runners
+func (p *WarmPool) target() int {
+	return max(0, min(p.size, p.slots.Size(), maxHostVMs-p.slots.Active()))
+}
+
+// trim deletes ready VMs beyond the target after the capacity shrank. VMs
+// still booting are trimmed once they are ready.
+func (p *WarmPool) trim() {
+	for len(p.tokens) > p.target() {
+		select {
+		case w := <-p.ready:
+			p.discard(w, "capacity reduced")
+			p.release()
+		default:
+			return
+		}
+	}
+}
+
+// drain deletes every VM still waiting in the pool
+func (p *WarmPool) drain() {
+	for {
+		select {
+		case w := <-p.ready:
+			p.discard(w, "shutdown")
+		default:
+			return
+		}
+	}
+}
+
+func (p *WarmPool) discard(w *WarmVM, reason string) {
+	p.log.Info("Discarding warm VM", zap.String("instance", w.Name), zap.String("reason", reason))
+	NewVMManager(p.cfg, p.log).Cleanup(context.Background(), w.Name)
+}
+
+// stale returns why w should not be handed out, or "" if it is usable
+func (p *WarmPool) stale(w *WarmVM) string {
+	switch {
+	case time.Since(w.CreatedAt) > p.maxAge:
+		return "expired"
+	case w.Identity != p.identity():
+		return "image changed"
+	}
+	return ""
+}
+
+// identity identifies what warm VMs are booted from: the image as resolved
+// on disk and, when clones resume from it, the snapshot
+func (p *WarmPool) identity() string {
+	h := sha256.New()
+	if err := writeImageIdentity(h, NewVMManager(p.cfg, p.log)); err != nil {
+		return ""
+	}
+	if p.snapshot != nil {
+		fmt.Fprintf(h, "snapshot=%s\n", p.snapshot.Identity())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// release frees a pool slot and wakes the filler to boot a replacement
+func (p *WarmPool) release() {
+	select {
+	case <-p.tokens:
+	default:
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// removeLeftovers deletes warm VMs left behind by a previous process, which
+// may have been built from an older image
+func (p *WarmPool) removeLeftovers() {
+	entries, err := os.ReadDir(filepath.Join(os.Getenv("HOME"), ".tart", "vms"))
+	if err != nil {
+		return
+	}
+	vm := NewVMManager(p.cfg, p.log)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), p.prefix) {
+			p.log.Info("Removing leftover warm VM", zap.String("instance", entry.Name()))
+			vm.Cleanup(context.Background(), entry.Name())
+		}
+	}
+}
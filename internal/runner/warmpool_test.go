@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+	"go.uber.org/zap"
+)
+
+func newTestWarmPool(t *testing.T, size int, slots *SlotPool) *WarmPool {
+	t.Helper()
+	// Keep tart out of reach so discarding a VM never touches the host
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	cfg := testConfig(t)
+	cfg.Options.WarmPool.Size = size
+	return NewWarmPool(cfg, zap.NewNop(), nil, slots)
+}
+
+func TestWarmPoolTarget(t *testing.T) {
+	slots := NewSlotPool(0)
+	pool := newTestWarmPool(t, 2, slots)
+	for _, tt := range []struct{ slots, want int }{{0, 0}, {1, 1}, {2, 2}, {5, 2}} {
+		slots.Resize(tt.slots)
+		if got := pool.target(); got != tt.want {
+			t.Errorf("target() with %d slots = %d, want %d", tt.slots, got, tt.want)
+		}
+	}
+}
+
+func TestWarmPoolTargetCountsActiveRunners(t *testing.T) {
+	slots := NewSlotPool(4)
+	pool := newTestWarmPool(t, 2, slots)
+	for active, want := range []int{2, 1, 0, 0} {
+		if got := pool.target(); got != want {
+			t.Errorf("target() with %d active runners = %d, want %d", active, got, want)
+		}
+		if _, err := slots.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWarmPoolAcquireDiscardsStale(t *testing.T) {
+	pool := newTestWarmPool(t, 2, NewSlotPool(2))
+	pool.maxAge = time.Hour
+	pool.tokens <- struct{}{}
+	pool.ready <- &WarmVM{Name: "runner_warm_1", CreatedAt: time.Now().Add(-2 * time.Hour)}
+	pool.tokens <- struct{}{}
+	pool.ready <- &WarmVM{Name: "runner_warm_2", CreatedAt: time.Now(), Identity: "older image"}
+
+	if w := pool.Acquire(); w != nil {
+		t.Errorf("Acquire() = %s, want nil for stale VMs", w.Name)
+	}
+	if len(pool.tokens) != 0 {
+		t.Errorf("%d tokens left after discarding stale VMs, want 0", len(pool.tokens))
+	}
+	select {
+	case <-pool.wake:
+	default:
+		t.Error("discarding a stale VM did not wake the filler")
+	}
+}
+
+func TestWarmPoolRecycleStale(t *testing.T) {
+	pool := newTestWarmPool(t, 2, NewSlotPool(2))
+	pool.maxAge = time.Hour
+	pool.tokens <- struct{}{}
+	pool.ready <- &WarmVM{Name: "runner_warm_1", CreatedAt: time.Now(), Identity: "older image"}
+	pool.tokens <- struct{}{}
+	pool.ready <- &WarmVM{Name: "runner_warm_2", CreatedAt: time.Now(), Identity: pool.identity()}
+
+	pool.recycleStale()
+	if len(pool.ready) != 1 || len(pool.tokens) != 1 {
+		t.Fatalf("after recycling: %d ready, %d tokens, want 1 each", len(pool.ready), len(pool.tokens))
+	}
+	if w := pool.Acquire(); w == nil || w.Name != "runner_warm_2" {
+		t.Errorf("Acquire() = %v, want runner_warm_2", w)
+	}
+}
+
+func TestWarmPoolRefillsAfterAcquire(t *testing.T) {
+	pool := newTestWarmPool(t, 1, NewSlotPool(1))
+	pool.tokens <- struct{}{}
+	pool.ready <- &WarmVM{Name: "runner_warm_0", CreatedAt: time.Now(), Identity: pool.identity()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if pool.Acquire() == nil {
+		t.Fatal("Acquire() = nil, want the ready VM")
+	}
+	// The replacement starts right away instead of on the next reaper tick
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.counter.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no replacement VM was started after Acquire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWarmPoolIdleWithoutCapacity(t *testing.T) {
+	pool := newTestWarmPool(t, 2, NewSlotPool(0))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pool.Run(ctx)
+	if n := pool.counter.Load(); n != 0 {
+		t.Errorf("warmed %d VMs with no runner slots", n)
+	}
+}
+
+func TestWarmPoolTrim(t *testing.T) {
+	slots := NewSlotPool(2)
+	pool := newTestWarmPool(t, 2, slots)
+	for _, name := range []string{"runner_warm_1", "runner_warm_2"} {
+		pool.tokens <- struct{}{}
+		pool.ready <- &WarmVM{Name: name, CreatedAt: time.Now()}
+	}
+
+	pool.trim()
+	if len(pool.ready) != 2 {
+		t.Fatalf("trim with room discarded VMs: %d ready", len(pool.ready))
+	}
+
+	slots.Resize(1)
+	pool.trim()
+	if len(pool.ready) != 1 || len(pool.tokens) != 1 {
+		t.Errorf("after shrinking to 1 slot: %d ready, %d tokens", len(pool.ready), len(pool.tokens))
+	}
+
+	slots.Resize(0)
+	pool.trim()
+	if len(pool.ready) != 0 || len(pool.tokens) != 0 {
+		t.Errorf("after shrinking to 0 slots: %d ready, %d tokens", len(pool.ready), len(pool.tokens))
+	}
+}
+
+func TestMountArgsPerSlot(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+	cfg.VM.Mounts = []config.MountConfig{{Name: "cache", HostPath: dir, PerSlot: true}}
+	vm := NewVMManager(cfg, zap.NewNop())
+
+	args, err := vm.mountArgs(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "cache:" + filepath.Join(dir, "slot-1"); len(args) != 2 || args[1] != want {
+		t.Errorf("mountArgs(1) = %q, want [--dir %s]", args, want)
+	}
+
+	if _, err := vm.mountArgs(warmSlotID); err == nil || !strings.Contains(err.Error(), "no runner slot") {
+		t.Errorf("mountArgs(warmSlotID) error = %v, want per_slot error", err)
+	}
+}
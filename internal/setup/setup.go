@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/rxtech-lab/rvmm/assets"
@@ -233,3 +234,26 @@ func CheckDependencies() error {
 
 	return nil
 }
+
+// HostCapacity returns the host CPU count and physical memory in megabytes
+func HostCapacity() (cpus int, memoryMB int, err error) {
+	out, err := exec.Command("sysctl", "-n", "hw.ncpu").Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("sysctl hw.ncpu failed: %w", err)
+	}
+	cpus, err = strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse hw.ncpu: %w", err)
+	}
+
+	out, err = exec.Command("sysctl", "-n", "hw.memsize").Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("sysctl hw.memsize failed: %w", err)
+	}
+	memBytes, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse hw.memsize: %w", err)
+	}
+
+	return cpus, int(memBytes / (1024 * 1024)), nil
+}
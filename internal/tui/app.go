@@ -77,6 +77,7 @@ type model struct {
 	busyLabel    string
 	runnerActive bool
 	runnerCancel context.CancelFunc
+	pullCancel   context.CancelFunc
 	windowWidth  int
 	windowHeight int
 	lastError    string
@@ -179,6 +180,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.runnerActive = false
 			m.runnerCancel = nil
 		}
+		if msg.action == actionPullImage {
+			m.stopPullIfActive()
+		}
 		if msg.err != nil {
 			m.lastError = msg.err.Error()
 		} else {
@@ -230,9 +234,14 @@ func (m model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q":
 		m.stopRunnerIfActive()
+		m.stopPullIfActive()
 		m.closeLogFile()
 		return m, tea.Quit
 	case "esc":
+		if m.pullCancel != nil {
+			m.stopPullIfActive()
+			return m, nil
+		}
 		if m.popMenu() {
 			return m, nil
 		}
@@ -265,8 +274,8 @@ func (m model) updateConfig(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.state = stateMenu
 		return m, nil
-	case "enter":
-		if m.configForm.focusIndex == len(m.configForm.inputs)-1 {
+	case "enter", "ctrl+s":
+		if msg.String() == "ctrl+s" || m.configForm.focusIndex == len(m.configForm.inputs)-1 {
 			cfg, err := m.configForm.toConfig()
 			if err != nil {
 				m.configForm.errMsg = err.Error()
@@ -340,10 +349,12 @@ func (m model) updatePullPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.lastError = "image name is required"
 			return m, nil
 		}
+		ctx, cancel := context.WithCancel(context.Background())
 		m.state = stateMenu
 		m.busy = true
 		m.busyLabel = "Pull image"
-		return m, tea.Batch(m.runPullImageCmd(image), m.spinner.Tick)
+		m.pullCancel = cancel
+		return m, tea.Batch(m.runPullImageCmd(ctx, image), m.spinner.Tick)
 	}
 
 	var cmd tea.Cmd
@@ -369,6 +380,14 @@ func (m *model) stopRunnerIfActive() {
 	m.runnerActive = false
 }
 
+// stopPullIfActive cancels a running image pull, which stops tart pull
+func (m *model) stopPullIfActive() {
+	if m.pullCancel != nil {
+		m.pullCancel()
+		m.pullCancel = nil
+	}
+}
+
 func (m *model) closeLogFile() {
 	if m.logCloser != nil {
 		_ = m.logCloser.Close()
@@ -418,9 +437,14 @@ func (m model) runPushImageCmd(target string) tea.Cmd {
 	}
 }
 
-func (m model) runPullImageCmd(target string) tea.Cmd {
+func (m model) runPullImageCmd(ctx context.Context, target string) tea.Cmd {
 	return func() tea.Msg {
-		if err := runCommandStreaming(m.logWriter, exec.Command("tart", "pull", target)); err != nil {
+		cfg := loadConfigOrDefault(m.configPath)
+		vm := runner.NewVMManager(cfg, m.logger)
+		onProgress := func(p runner.PullProgress) {
+			_, _ = fmt.Fprintln(m.logWriter, p.String())
+		}
+		if err := vm.PullRef(ctx, target, onProgress); err != nil {
 			return taskDoneMsg{action: actionPullImage, err: err}
 		}
 		return taskDoneMsg{action: actionPullImage, err: nil}
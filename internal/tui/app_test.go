@@ -0,0 +1,28 @@
+package tui
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestEscCancelsPull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := model{state: stateMenu, busy: true, busyLabel: "Pull image", pullCancel: cancel}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if ctx.Err() == nil {
+		t.Fatal("esc did not cancel the pull")
+	}
+	if updated.(model).pullCancel != nil {
+		t.Error("pullCancel kept after cancelling")
+	}
+
+	// The pull command reports back once tart has stopped
+	updated, _ = updated.(model).Update(taskDoneMsg{action: actionPullImage, err: ctx.Err()})
+	if got := updated.(model); got.busy || got.lastError == "" {
+		t.Errorf("after the pull ended: busy = %v, lastError = %q", got.busy, got.lastError)
+	}
+}
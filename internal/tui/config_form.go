@@ -2,7 +2,6 @@ package tui
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -10,14 +9,17 @@ import (
 	"github.com/rxtech-lab/rvmm/internal/config"
 )
 
+// configField is a form row generated from the config struct tags
 type configField struct {
-	key      string
-	label    string
-	required bool
-	secret   bool
+	config.FieldInfo
+	// choices are cycled with left/right/space instead of typing, for
+	// booleans and enums
+	choices []string
 }
 
 type configForm struct {
+	// base is the loaded config; the form only changes the fields edited
+	base       *config.Config
 	fields     []configField
 	inputs     []textinput.Model
 	focusIndex int
@@ -25,58 +27,84 @@ type configForm struct {
 }
 
 func newConfigForm(cfg *config.Config) configForm {
-	fields := []configField{
-		{key: "github.api_token", label: "GitHub API token", required: true, secret: true},
-		{key: "github.registration_endpoint", label: "Registration endpoint", required: true},
-		{key: "github.runner_url", label: "Runner URL", required: true},
-		{key: "github.runner_name", label: "Runner name"},
-		{key: "github.runner_labels", label: "Runner labels (comma)"},
-		{key: "github.runner_group", label: "Runner group (optional)"},
-		{key: "vm.username", label: "VM username", required: true},
-		{key: "vm.password", label: "VM password", required: true, secret: true},
-		{key: "vm.display", label: "VM display (WxH)"},
-		{key: "registry.url", label: "Registry URL"},
-		{key: "registry.image_name", label: "Registry image name", required: true},
-		{key: "registry.username", label: "Registry username"},
-		{key: "registry.password", label: "Registry password", secret: true},
-		{key: "options.log_file", label: "Log file"},
-		{key: "options.max_concurrent_runners", label: "Max concurrent runners"},
-		{key: "options.shutdown_flag_file", label: "Shutdown flag file"},
-		{key: "options.working_directory", label: "Working directory"},
-		{key: "daemon.label", label: "Daemon label"},
-		{key: "daemon.plist_path", label: "Daemon plist path"},
-		{key: "daemon.user", label: "Daemon user"},
-	}
+	var fields []configField
+	var inputs []textinput.Model
+	for _, info := range config.Fields() {
+		value, err := cfg.FieldText(info.Path)
+		if err != nil {
+			continue
+		}
+
+		field := configField{FieldInfo: info}
+		switch {
+		case info.Kind == config.FieldBool:
+			field.choices = []string{"false", "true"}
+		case len(info.Enum) > 0:
+			field.choices = info.Enum
+			if !contains(field.choices, value) {
+				field.choices = append([]string{value}, field.choices...)
+			}
+		}
 
-	inputs := make([]textinput.Model, len(fields))
-	for i, field := range fields {
 		input := textinput.New()
 		input.CharLimit = 512
+		if info.Kind == config.FieldList || info.Kind == config.FieldObjects {
+			input.CharLimit = 8192
+		}
 		input.Width = 50
-		input.SetValue(getFieldValue(cfg, field.key))
-		if field.secret {
+		input.SetValue(value)
+		// References such as env:NAME are not secret themselves
+		if info.Secret && !config.IsSecretRef(value) {
 			input.EchoMode = textinput.EchoPassword
 			input.EchoCharacter = '*'
 		}
-		inputs[i] = input
+
+		fields = append(fields, field)
+		inputs = append(inputs, input)
 	}
 
 	if len(inputs) > 0 {
 		inputs[0].Focus()
 	}
 
-	return configForm{fields: fields, inputs: inputs, focusIndex: 0}
+	return configForm{base: cfg, fields: fields, inputs: inputs, focusIndex: 0}
 }
 
 func (f configForm) Update(msg tea.Msg) (configForm, tea.Cmd) {
+	if len(f.inputs) == 0 {
+		return f, nil
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && len(f.fields[f.focusIndex].choices) > 0 {
+		f.cycleChoice(key.String())
+		return f, nil
+	}
+
 	var cmd tea.Cmd
-	for i := range f.inputs {
-		if i == f.focusIndex {
-			f.inputs[i], cmd = f.inputs[i].Update(msg)
-			return f, cmd
+	f.inputs[f.focusIndex], cmd = f.inputs[f.focusIndex].Update(msg)
+	return f, cmd
+}
+
+// cycleChoice steps a boolean or enum field through its choices
+func (f *configForm) cycleChoice(key string) {
+	choices := f.fields[f.focusIndex].choices
+	step := 0
+	switch key {
+	case "right", " ":
+		step = 1
+	case "left":
+		step = len(choices) - 1
+	default:
+		return
+	}
+
+	current := 0
+	for i, choice := range choices {
+		if choice == f.inputs[f.focusIndex].Value() {
+			current = i
+			break
 		}
 	}
-	return f, nil
+	f.inputs[f.focusIndex].SetValue(choices[(current+step)%len(choices)])
 }
 
 func (f configForm) updateFocus(key string) configForm {
@@ -97,145 +125,47 @@ func (f configForm) updateFocus(key string) configForm {
 	return f
 }
 
+// toConfig applies the form to a copy of the loaded config and validates it
 func (f configForm) toConfig() (*config.Config, error) {
-	cfg := defaultConfig()
-	for i, field := range f.fields {
-		value := strings.TrimSpace(f.inputs[i].Value())
-		if field.required && value == "" {
-			return nil, fmt.Errorf("%s is required", field.label)
-		}
-		setFieldValue(cfg, field.key, value)
+	cfg, err := f.apply()
+	if err != nil {
+		return nil, err
 	}
-
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
-
 	return cfg, nil
 }
 
-func getFieldValue(cfg *config.Config, key string) string {
-	switch key {
-	case "github.api_token":
-		return cfg.GitHub.APIToken
-	case "github.registration_endpoint":
-		return cfg.GitHub.RegistrationEndpoint
-	case "github.runner_url":
-		return cfg.GitHub.RunnerURL
-	case "github.runner_name":
-		return cfg.GitHub.RunnerName
-	case "github.runner_labels":
-		return strings.Join(cfg.GitHub.RunnerLabels, ",")
-	case "github.runner_group":
-		return cfg.GitHub.RunnerGroup
-	case "vm.username":
-		return cfg.VM.Username
-	case "vm.password":
-		return cfg.VM.Password
-	case "vm.display":
-		return cfg.VM.Display
-	case "registry.url":
-		return cfg.Registry.URL
-	case "registry.image_name":
-		return cfg.Registry.ImageName
-	case "registry.username":
-		return cfg.Registry.Username
-	case "registry.password":
-		return cfg.Registry.Password
-	case "options.log_file":
-		return cfg.Options.LogFile
-	case "options.max_concurrent_runners":
-		return strconv.Itoa(cfg.Options.MaxConcurrentRunners)
-	case "options.shutdown_flag_file":
-		return cfg.Options.ShutdownFlagFile
-	case "options.working_directory":
-		return cfg.Options.WorkingDirectory
-	case "daemon.label":
-		return cfg.Daemon.Label
-	case "daemon.plist_path":
-		return cfg.Daemon.PlistPath
-	case "daemon.user":
-		return cfg.Daemon.User
-	default:
-		return ""
+// apply copies the loaded config and sets the edited fields, so the others
+// keep their loaded values exactly
+func (f configForm) apply() (*config.Config, error) {
+	cfg := defaultConfig()
+	if f.base != nil {
+		copied := *f.base
+		cfg = &copied
 	}
-}
 
-func setFieldValue(cfg *config.Config, key, value string) {
-	switch key {
-	case "github.api_token":
-		cfg.GitHub.APIToken = value
-	case "github.registration_endpoint":
-		cfg.GitHub.RegistrationEndpoint = value
-	case "github.runner_url":
-		cfg.GitHub.RunnerURL = value
-	case "github.runner_name":
-		if value != "" {
-			cfg.GitHub.RunnerName = value
-		}
-	case "github.runner_labels":
-		if value != "" {
-			cfg.GitHub.RunnerLabels = splitCSV(value)
-		}
-	case "github.runner_group":
-		cfg.GitHub.RunnerGroup = value
-	case "vm.username":
-		cfg.VM.Username = value
-	case "vm.password":
-		cfg.VM.Password = value
-	case "vm.display":
-		if value != "" {
-			cfg.VM.Display = value
-		}
-	case "registry.url":
-		cfg.Registry.URL = value
-	case "registry.image_name":
-		cfg.Registry.ImageName = value
-	case "registry.username":
-		cfg.Registry.Username = value
-	case "registry.password":
-		cfg.Registry.Password = value
-	case "options.log_file":
-		if value != "" {
-			cfg.Options.LogFile = value
-		}
-	case "options.max_concurrent_runners":
-		if value != "" {
-			if n, err := strconv.Atoi(value); err == nil && n >= 1 {
-				cfg.Options.MaxConcurrentRunners = n
-			}
-		}
-	case "options.shutdown_flag_file":
-		if value != "" {
-			cfg.Options.ShutdownFlagFile = value
-		}
-	case "options.working_directory":
-		if value != "" {
-			cfg.Options.WorkingDirectory = value
-		}
-	case "daemon.label":
-		if value != "" {
-			cfg.Daemon.Label = value
+	for i, field := range f.fields {
+		value := f.inputs[i].Value()
+		if current, _ := cfg.FieldText(field.Path); value != current {
+			value = strings.TrimSpace(value)
 		}
-	case "daemon.plist_path":
-		if value != "" {
-			cfg.Daemon.PlistPath = value
+		if field.Required && strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("%s is required", field.Label)
 		}
-	case "daemon.user":
-		if value != "" {
-			cfg.Daemon.User = value
+		if err := cfg.SetFieldText(field.Path, value); err != nil {
+			return nil, err
 		}
 	}
+	return cfg, nil
 }
 
-func splitCSV(value string) []string {
-	parts := strings.Split(value, ",")
-	labels := make([]string, 0, len(parts))
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		if trimmed != "" {
-			labels = append(labels, trimmed)
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
-	return labels
+	return false
 }
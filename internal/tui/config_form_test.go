@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/rxtech-lab/rvmm/internal/config"
+)
+
+// objectFixtures are one-line YAML values for the list-of-object fields
+var objectFixtures = map[string]string{
+	"vm.bootstrap":                `[{name: "git, config", script: "echo 'a: b'", env: ["A=1,2", "B=x"]}, {file: /opt/x.sh}]`,
+	"vm.readiness_probes":         `[{type: network, target: "https://github.com"}, {name: "xcode, selected", type: command, command: "xcode-select -p"}]`,
+	"vm.mounts":                   `[{name: cache, host_path: "/Users/admin/a,b", per_slot: true}, {name: tools, host_path: /opt/tools, read_only: true}]`,
+	"options.hooks.after_clone":   `[{command: /usr/local/bin/register, args: ["--pool", "a,b"], timeout: 30s}]`,
+	"options.hooks.after_ip":      `[{command: "echo, hi"}]`,
+	"options.hooks.before_runner": `[{command: /bin/true}]`,
+	"options.hooks.after_job":     `[{command: /bin/true, args: ["#x"]}]`,
+	"options.hooks.on_failure":    `[{command: /bin/false, timeout: 1m}]`,
+	"options.schedule":            `[{name: night, days: [mon, tue], start: "22:00", end: "06:00", timezone: Europe/Berlin, max_concurrent_runners: 1}]`,
+}
+
+// populatedConfig sets every form field to a value that differs from the
+// default, including list items with commas and YAML syntax
+func populatedConfig(t *testing.T, dir string) *config.Config {
+	t.Helper()
+	include := "base, shared.yaml"
+	if err := os.WriteFile(filepath.Join(dir, include), []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	for i, field := range config.Fields() {
+		var text string
+		switch {
+		case field.Path == "include":
+			text = `["` + include + `"]`
+		case len(field.Enum) > 0:
+			text = field.Enum[len(field.Enum)-1]
+		case field.Kind == config.FieldString:
+			text = `value, "` + strconv.Itoa(i) + `": #x`
+		case field.Kind == config.FieldInt:
+			text = strconv.Itoa(i)
+		case field.Kind == config.FieldBool:
+			text = "true"
+		case field.Kind == config.FieldList:
+			text = `[self-hosted, "a,b", "key: value", " padded "]`
+		case field.Kind == config.FieldObjects:
+			var ok bool
+			if text, ok = objectFixtures[field.Path]; !ok {
+				t.Fatalf("no fixture for %s", field.Path)
+			}
+		}
+		if err := cfg.SetFieldText(field.Path, text); err != nil {
+			t.Fatalf("SetFieldText(%s): %v", field.Path, err)
+		}
+	}
+	return cfg
+}
+
+func TestConfigFormRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rvmm.yaml")
+	want := populatedConfig(t, dir)
+	if err := writeConfig(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"unedited", "saved again"} {
+		loaded, err := config.LoadRaw(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if diff := config.Diff(want, loaded); len(diff) > 0 {
+			t.Fatalf("%s: fields changed by load: %v", name, diff)
+		}
+
+		form := newConfigForm(loaded)
+		if len(form.fields) != len(config.Fields()) {
+			t.Fatalf("%s: form has %d fields, want %d", name, len(form.fields), len(config.Fields()))
+		}
+		saved, err := form.apply()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if diff := config.Diff(want, saved); len(diff) > 0 {
+			t.Fatalf("%s: fields changed by the form: %v", name, diff)
+		}
+		if err := writeConfig(path, saved); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestConfigFormEditList(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rvmm.yaml")
+	if err := writeConfig(path, populatedConfig(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := config.LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := newConfigForm(loaded)
+	for i, field := range form.fields {
+		if field.Path == "github.runner_labels" {
+			form.inputs[i].SetValue(`[macOS, "xcode,15"]`)
+		}
+	}
+	cfg, err := form.apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := config.LoadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.GitHub.RunnerLabels; len(got) != 2 || got[1] != "xcode,15" {
+		t.Errorf("runner_labels = %q, want [macOS xcode,15]", got)
+	}
+	if diff := config.Diff(cfg, reloaded); len(diff) > 0 {
+		t.Errorf("fields changed by save: %v", diff)
+	}
+}
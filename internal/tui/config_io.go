@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 
 	"github.com/rxtech-lab/rvmm/internal/config"
-	"gopkg.in/yaml.v3"
 )
 
 func loadConfig(path string) (*config.Config, error) {
@@ -33,28 +32,19 @@ func loadConfigOrDefault(path string) *config.Config {
 	return defaultConfig()
 }
 
-func defaultConfig() *config.Config {
-	return &config.Config{
-		GitHub: config.GitHubConfig{
-			RunnerName:   "runner",
-			RunnerLabels: []string{"self-hosted", "arm64"},
-		},
-		VM: config.VMConfig{
-			Username: "admin",
-			Password: "admin",
-			Display:  "3840x2160",
-		},
-		Options: config.OptionsConfig{
-			LogFile:          "runner.log",
-			ShutdownFlagFile: ".shutdown",
-			WorkingDirectory: "/Users/admin/vm",
-		},
-		Daemon: config.DaemonConfig{
-			Label:     "com.mirego.ekiden",
-			PlistPath: "/Library/LaunchDaemons/com.mirego.ekiden.plist",
-			User:      "admin",
-		},
+// loadRawConfigOrDefault loads the config for editing, keeping secret
+// references such as env:GITHUB_TOKEN instead of the secrets they point to
+func loadRawConfigOrDefault(path string) *config.Config {
+	cfg, err := config.LoadRaw(path)
+	if err == nil {
+		return cfg
 	}
+
+	return defaultConfig()
+}
+
+func defaultConfig() *config.Config {
+	return config.Default()
 }
 
 func defaultConfigPath() string {
@@ -65,19 +55,11 @@ func defaultConfigPath() string {
 	return filepath.Join(workingDir, "rvmm.yaml")
 }
 
+// writeConfig saves the fields changed in the form into the config files,
+// keeping their comments; see config.Save
 func writeConfig(path string, cfg *config.Config) error {
 	if cfg == nil {
 		return errors.New("config is nil")
 	}
-
-	data, err := yaml.Marshal(cfg)
-	if err != nil {
-		return err
-	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return err
-	}
-
-	return nil
+	return config.Save(path, cfg)
 }
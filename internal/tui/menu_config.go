@@ -13,7 +13,7 @@ func (configMenuItem) Description() string {
 }
 
 func (configMenuItem) OnSelect(m *model) (tea.Model, tea.Cmd) {
-	cfg := loadConfigOrDefault(m.configPath)
+	cfg := loadRawConfigOrDefault(m.configPath)
 	m.configForm = newConfigForm(cfg)
 	m.state = stateConfig
 	return *m, nil
@@ -14,6 +14,7 @@ func (quitMenuItem) Description() string {
 
 func (quitMenuItem) OnSelect(m *model) (tea.Model, tea.Cmd) {
 	m.stopRunnerIfActive()
+	m.stopPullIfActive()
 	m.closeLogFile()
 	return *m, tea.Quit
 }
@@ -12,6 +12,9 @@ func (m model) viewMenu() string {
 	if m.busy {
 		status = "Busy: " + m.busyLabel
 	}
+	if m.pullCancel != nil {
+		status = status + " (press esc to cancel)"
+	}
 	if m.runnerActive {
 		status = status + " | Runner active (press s to stop)"
 	}
@@ -44,24 +47,62 @@ func (m model) viewMenu() string {
 }
 
 func (m model) viewConfig() string {
-	var b strings.Builder
-	b.WriteString("Edit configuration (esc to cancel)\n\n")
-	for i, input := range m.configForm.inputs {
-		field := m.configForm.fields[i]
+	form := m.configForm
+
+	// Render every row, then show the window around the focused one
+	var lines []string
+	focusLine := 0
+	section := ""
+	for i, input := range form.inputs {
+		field := form.fields[i]
+		if field.Section != section {
+			section = field.Section
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "["+section+"]")
+		}
 		cursor := " "
-		if m.configForm.focusIndex == i {
+		if form.focusIndex == i {
 			cursor = ">"
+			focusLine = len(lines)
 		}
 		required := ""
-		if field.required {
+		if field.Required {
 			required = "*"
 		}
-		b.WriteString(fmt.Sprintf("%s %s%s: %s\n", cursor, field.label, required, input.View()))
+		lines = append(lines, fmt.Sprintf("%s %s%s: %s", cursor, field.Label, required, input.View()))
+	}
+
+	// Leave room for the title, help, error and tips
+	height := m.windowHeight - 8
+	if height > 0 && len(lines) > height {
+		start := focusLine - height/2
+		if start < 0 {
+			start = 0
+		}
+		if start > len(lines)-height {
+			start = len(lines) - height
+		}
+		lines = lines[start : start+height]
+	}
+
+	var b strings.Builder
+	b.WriteString("Edit configuration (esc to cancel)\n\n")
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n")
+	if len(form.fields) > 0 {
+		field := form.fields[form.focusIndex]
+		help := field.Path
+		if field.Help != "" {
+			help += ": " + field.Help
+		}
+		b.WriteString("\n" + help + "\n")
 	}
-	if m.configForm.errMsg != "" {
-		b.WriteString("\nError: " + m.configForm.errMsg + "\n")
+	if form.errMsg != "" {
+		b.WriteString("\nError: " + form.errMsg + "\n")
 	}
-	b.WriteString("\nTab/Up/Down to move, Enter to save")
+	b.WriteString("\nTab/Up/Down to move, Left/Right/Space to change choices, Ctrl+S or Enter on the last field to save")
 	return b.String()
 }
 
@@ -7,10 +7,12 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"syscall"
 
 	"github.com/rxtech-lab/rvmm/internal/config"
+	"github.com/rxtech-lab/rvmm/internal/daemon"
 	"github.com/rxtech-lab/rvmm/internal/monitor"
 	"github.com/rxtech-lab/rvmm/internal/posthog"
 	"github.com/rxtech-lab/rvmm/internal/runner"
@@ -27,6 +29,17 @@ func main() {
 		monitorHeadless()
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "concurrency" {
+		concurrencyCommand()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "daemon" {
+		daemonCommand()
+		return
+	}
 	tui.Run()
 }
 
@@ -49,17 +62,141 @@ func runHeadless() {
 	if err != nil {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
+	for _, warning := range cfg.Warnings {
+		logger.Warn("Config warning", zap.String("warning", warning))
+	}
 
 	if err := cfg.Validate(); err != nil {
 		logger.Fatal("Invalid config", zap.Error(err))
 	}
 
+	// SIGHUP reloads the same config file
+	reload := func() (*config.Config, error) {
+		return config.Load(*configPath)
+	}
+
 	logger.Info("Starting runner in headless mode")
-	if err := runner.Run(context.Background(), logger, cfg); err != nil {
+	if err := runner.RunWithReload(context.Background(), logger, cfg, reload); err != nil {
 		logger.Fatal("Runner exited with error", zap.Error(err))
 	}
 }
 
+// concurrencyCommand changes the concurrency of a running runner through its
+// control socket: `rvmm concurrency [status|reset|<n>]`
+func concurrencyCommand() {
+	fs := flag.NewFlagSet("concurrency", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	client, err := runner.NewControlClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	var status *runner.CapacityStatus
+	switch arg := fs.Arg(0); arg {
+	case "", "status":
+		status, err = client.Status()
+	case "reset":
+		status, err = client.ResetConcurrency()
+	default:
+		n, convErr := strconv.Atoi(arg)
+		if convErr != nil {
+			fmt.Fprintf(os.Stderr, "usage: rvmm concurrency [-config path] [status|reset|<n>]\n")
+			os.Exit(2)
+		}
+		status, err = client.SetConcurrency(n)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("max_concurrent_runners: %d\n", status.MaxConcurrentRunners)
+	fmt.Printf("active_runners: %d\n", status.ActiveRunners)
+	if status.Override != nil {
+		fmt.Printf("override: %d\n", *status.Override)
+	}
+	if status.ScheduleWindow != "" {
+		fmt.Printf("schedule_window: %s\n", status.ScheduleWindow)
+	}
+}
+
+// daemonCommand installs and controls the runner or monitor service with
+// launchd or systemd: `rvmm daemon [-monitor] install|uninstall|status`
+func daemonCommand() {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	monitorService := fs.Bool("monitor", false, "manage the log monitor instead of the runner")
+	if err := fs.Parse(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch fs.Arg(0) {
+	case "install":
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.Source == "" {
+			fmt.Fprintln(os.Stderr, "no config file found; pass -config")
+			os.Exit(1)
+		}
+		if *monitorService {
+			if !cfg.PostHog.Enabled {
+				fmt.Fprintln(os.Stderr, "PostHog must be enabled in config")
+				os.Exit(1)
+			}
+			err = daemon.InstallMonitor(logger, cfg, cfg.Source, os.Stdout)
+		} else {
+			err = daemon.Install(logger, cfg, cfg.Source, os.Stdout)
+		}
+	case "uninstall":
+		if *monitorService {
+			err = daemon.UninstallMonitor(logger, cfg, os.Stdout)
+		} else {
+			err = daemon.Uninstall(logger, cfg, os.Stdout)
+		}
+	case "", "status":
+		if *monitorService {
+			err = daemon.StatusMonitor(logger, cfg, os.Stdout)
+		} else {
+			err = daemon.Status(logger, cfg, os.Stdout)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: rvmm daemon [-config path] [-monitor] install|uninstall|status\n")
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
 func monitorHeadless() {
 	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
 	configPath := fs.String("config", "", "path to config file")
@@ -79,6 +216,9 @@ func monitorHeadless() {
 	if err != nil {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
+	for _, warning := range cfg.Warnings {
+		logger.Warn("Config warning", zap.String("warning", warning))
+	}
 
 	if err := cfg.Validate(); err != nil {
 		logger.Fatal("Invalid config", zap.Error(err))